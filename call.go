@@ -11,5 +11,9 @@ type Call struct {
 	Vars      *ast.Vars
 	SshClient *taskSsh.SshClient
 	Silent    bool
-	Indirect  bool // True if the task was called by another task
+	Indirect  bool   // True if the task was called by another task
+	Host      string // Set when the task is fanned out to several SSH hosts
+	// SshForwards are the special variables exposing the addresses of the
+	// SSH forwards opened for the task
+	SshForwards map[string]string
 }
@@ -117,12 +117,17 @@ func run() error {
 	if err := e.Setup(); err != nil {
 		return err
 	}
+	defer e.Close()
 
 	if flags.ClearCache {
 		cachePath := filepath.Join(e.TempDir.Remote, "remote")
 		return os.RemoveAll(cachePath)
 	}
 
+	if flags.Plugins {
+		return e.ListPlugins()
+	}
+
 	listOptions := task.NewListOptions(
 		flags.List,
 		flags.ListAll,
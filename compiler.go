@@ -3,7 +3,11 @@ package task
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,6 +21,7 @@ import (
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/templater"
 	"github.com/go-task/task/v3/internal/version"
+	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
@@ -28,7 +33,12 @@ type Compiler struct {
 	TaskfileEnv  *ast.Vars
 	TaskfileVars *ast.Vars
 
-	Logger *logger.Logger
+	Logger  *logger.Logger
+	Plugins taskfile.Plugins
+	// InterpOptions are the interp_options of the main Taskfile.
+	InterpOptions *ast.InterpOptions
+	// RootTaskfile is the location of the main Taskfile.
+	RootTaskfile string
 
 	dynamicCache   map[string]string
 	muDynamicCache sync.Mutex
@@ -56,7 +66,7 @@ func (c *Compiler) getVariables(t *ast.Task, call *Call, evaluateShVars bool) (*
 		result.Set(k, ast.Var{Value: v})
 	}
 
-	getRangeFunc := func(dir string) func(k string, v ast.Var) error {
+	getRangeFunc := func(dir string, t *ast.Task) func(k string, v ast.Var) error {
 		return func(k string, v ast.Var) error {
 			cache := &templater.Cache{Vars: result}
 			// Replace values
@@ -77,12 +87,12 @@ func (c *Compiler) getVariables(t *ast.Task, call *Call, evaluateShVars bool) (*
 				return err
 			}
 			// If the variable is already set, we can set it and return
-			if newVar.Value != nil || newVar.Sh == nil {
+			if newVar.Value != nil || newVar.Sh == nil && newVar.Plugin == "" {
 				result.Set(k, ast.Var{Value: newVar.Value})
 				return nil
 			}
 			// If the variable is dynamic, we need to resolve it first
-			static, err := c.HandleDynamicVar(newVar, dir, env.GetFromVars(result))
+			static, err := c.HandleDynamicVar(newVar, t, dir, env.GetFromVars(result), result)
 			if err != nil {
 				return err
 			}
@@ -90,7 +100,7 @@ func (c *Compiler) getVariables(t *ast.Task, call *Call, evaluateShVars bool) (*
 			return nil
 		}
 	}
-	rangeFunc := getRangeFunc(c.Dir)
+	rangeFunc := getRangeFunc(c.Dir, nil)
 
 	var taskRangeFunc func(k string, v ast.Var) error
 	if t != nil {
@@ -102,7 +112,7 @@ func (c *Compiler) getVariables(t *ast.Task, call *Call, evaluateShVars bool) (*
 			return nil, err
 		}
 		dir = filepathext.SmartJoin(c.Dir, dir)
-		taskRangeFunc = getRangeFunc(dir)
+		taskRangeFunc = getRangeFunc(dir, t)
 	}
 
 	for k, v := range c.TaskfileEnv.All() {
@@ -146,7 +156,14 @@ func (c *Compiler) getVariables(t *ast.Task, call *Call, evaluateShVars bool) (*
 	return result, nil
 }
 
-func (c *Compiler) HandleDynamicVar(v ast.Var, dir string, e []string) (string, error) {
+// HandleDynamicVar resolves a dynamic variable, with vars being the variables
+// resolved before it. JavaScript variables run in the sandbox of the task they
+// belong to, or of the main Taskfile when it is nil.
+func (c *Compiler) HandleDynamicVar(v ast.Var, t *ast.Task, dir string, e []string, vars *ast.Vars) (string, error) {
+	if v.Plugin != "" {
+		return c.handlePluginVar(v, vars)
+	}
+
 	c.muDynamicCache.Lock()
 	defer c.muDynamicCache.Unlock()
 
@@ -188,11 +205,11 @@ func (c *Compiler) HandleDynamicVar(v ast.Var, dir string, e []string) (string,
 			Stdout:  &stdout,
 			Stderr:  c.Logger.Stderr,
 		}
-		taskJs.Setup()
-		js, err := taskJs.NewJavaScript()
+		js, err := taskJs.Get(c.jsSandbox(t, dir))
 		if err != nil {
-			return "", fmt.Errorf("js: uninitialized")
+			return "", fmt.Errorf("js: uninitialized: %w", err)
 		}
+		defer js.Release()
 		if _, err := js.Eval(opts); err != nil {
 			return "", fmt.Errorf(`task: Script "%s" failed: %s`, opts.Script, err)
 		}
@@ -220,6 +237,65 @@ func (c *Compiler) HandleDynamicVar(v ast.Var, dir string, e []string) (string,
 	return result, nil
 }
 
+// handlePluginVar calls the plugin export of a dynamic variable, which reads
+// the variables resolved before it with task_var. Its value is the output of
+// the export, after what the plugin wrote to its stdout, and its stderr and
+// logs too when they are captured. Otherwise, they go to the stderr of Task.
+//
+// Results are cached by call and by the variables the plugin may read, which
+// aren't cached when they can't be encoded. The cache isn't locked during the
+// call, as the plugin may run tasks whose variables are resolved in the
+// meantime.
+func (c *Compiler) handlePluginVar(v ast.Var, vars *ast.Vars) (string, error) {
+	input, err := pluginCmdInput(v.Input)
+	if err != nil {
+		return "", fmt.Errorf("task: Plugin call %q: invalid input: %w", v.Plugin, err)
+	}
+	pluginVars := vars.ToCacheMap()
+	var key string
+	if encoded, err := json.Marshal(pluginVars); err == nil {
+		sum := sha256.Sum256(encoded)
+		key = fmt.Sprintf("%s\x00%t\x00%s\x00%x", v.Plugin, v.Logs, input, sum)
+		c.muDynamicCache.Lock()
+		result, ok := c.dynamicCache[key]
+		c.muDynamicCache.Unlock()
+		if ok {
+			return result, nil
+		}
+	}
+
+	pluginName, export, _ := strings.Cut(v.Plugin, ".")
+	if !c.Plugins.Has(pluginName, export) {
+		return "", fmt.Errorf(`task: invalid plugin call "%s": plugin %q has no export %q`, v.Plugin, pluginName, export)
+	}
+	var stdout bytes.Buffer
+	var stderr io.Writer = c.Logger.Stderr
+	if v.Logs {
+		stderr = &stdout
+	}
+	ctx := taskfile.WithPluginOutput(context.Background(), &stdout, stderr)
+	out, err := c.Plugins.Call(ctx, pluginName, export, pluginVars, input)
+	if err != nil {
+		return "", err
+	}
+	stdout.Write(out)
+
+	result := strings.TrimSuffix(stdout.String(), "\r\n")
+	result = strings.TrimSuffix(result, "\n")
+
+	c.muDynamicCache.Lock()
+	defer c.muDynamicCache.Unlock()
+	if key != "" {
+		if c.dynamicCache == nil {
+			c.dynamicCache = make(map[string]string, 30)
+		}
+		c.dynamicCache[key] = result
+	}
+	c.Logger.VerboseErrf(logger.Magenta, "task: dynamic variable: %q result: %q\n", v.Plugin, result)
+
+	return result, nil
+}
+
 // ResetCache clear the dynamic variables cache
 func (c *Compiler) ResetCache() {
 	c.muDynamicCache.Lock()
@@ -249,6 +325,7 @@ func (c *Compiler) getSpecialVars(t *ast.Task, call *Call) (map[string]string, e
 	}
 	if call != nil {
 		allVars["ALIAS"] = call.Task
+		maps.Copy(allVars, call.SshForwards)
 	} else {
 		allVars["ALIAS"] = ""
 	}
@@ -40,6 +40,12 @@ const (
 	CodeTaskMissingRequiredVars
 	CodeTaskNotAllowedVars
 	CodeTaskSSHConnectError
+	CodeTaskSSHHostsFailed
+	CodeTaskSSHCommandError
+	CodeTaskSSHHostNotTrusted
+	CodeTaskSSHHostKeyChanged
+	CodeTaskPluginExitError
+	CodeTaskJSError
 )
 
 // TaskError extends the standard error interface with a Code method. This code will
@@ -71,3 +77,8 @@ func As(err error, target any) bool {
 func Unwrap(err error) error {
 	return errors.Unwrap(err)
 }
+
+// Join wraps the standard errors.Join function so that we don't need to alias that package.
+func Join(errs ...error) error {
+	return errors.Join(errs...)
+}
@@ -219,3 +219,160 @@ func (err *TaskSSHConnectError) Error() string {
 func (err *TaskSSHConnectError) Code() int {
 	return CodeTaskSSHConnectError
 }
+
+// TaskSSHHostsError is returned when a task fanned out to several SSH hosts
+// failed on at least one of them.
+type TaskSSHHostsError struct {
+	TaskName    string
+	FailedHosts []string
+	Err         error
+}
+
+func (err *TaskSSHHostsError) Error() string {
+	return fmt.Sprintf(
+		`task: Task %q failed on %d host(s) (%s): %v`,
+		err.TaskName,
+		len(err.FailedHosts),
+		strings.Join(err.FailedHosts, ", "),
+		err.Err,
+	)
+}
+
+func (err *TaskSSHHostsError) Code() int {
+	return CodeTaskSSHHostsFailed
+}
+
+func (err *TaskSSHHostsError) Unwrap() error {
+	return err.Err
+}
+
+// TaskSSHCommandError is returned when a command run on an SSH host fails. It
+// unwraps to the exit status of the remote command so it is handled like a
+// local command failure.
+type TaskSSHCommandError struct {
+	Host     string
+	Command  string
+	ExitCode int
+	// Signal is the name of the signal which killed the remote command, if any.
+	Signal string
+	// Err is the cause when the connection was lost before the command exited.
+	Err error
+}
+
+func (err *TaskSSHCommandError) Error() string {
+	switch {
+	case err.Err != nil:
+		return fmt.Sprintf(`task: Command %q on host %q lost its connection: %v`, err.Command, err.Host, err.Err)
+	case err.Signal != "":
+		return fmt.Sprintf(`task: Command %q on host %q was killed by signal %s`, err.Command, err.Host, err.Signal)
+	default:
+		return fmt.Sprintf(`task: Command %q on host %q exited with code %d`, err.Command, err.Host, err.ExitCode)
+	}
+}
+
+func (err *TaskSSHCommandError) Code() int {
+	return CodeTaskSSHCommandError
+}
+
+func (err *TaskSSHCommandError) Unwrap() error {
+	return interp.ExitStatus(err.ExitCode)
+}
+
+// TaskSSHHostNotTrustedError is returned when the user does not trust the key
+// of an SSH host seen for the first time.
+type TaskSSHHostNotTrustedError struct {
+	Host        string
+	Fingerprint string
+}
+
+func (err *TaskSSHHostNotTrustedError) Error() string {
+	return fmt.Sprintf(`task: Host %q with key %s not trusted by user`, err.Host, err.Fingerprint)
+}
+
+func (err *TaskSSHHostNotTrustedError) Code() int {
+	return CodeTaskSSHHostNotTrusted
+}
+
+// TaskSSHHostKeyChangedError is returned when an SSH host presents another key
+// than the one it is known with, which may be a man-in-the-middle attack.
+type TaskSSHHostKeyChangedError struct {
+	Host string
+	// KnownHostsFile and Line locate the known key.
+	KnownHostsFile   string
+	Line             int
+	KnownFingerprint string
+	Fingerprint      string
+}
+
+func (err *TaskSSHHostKeyChangedError) Error() string {
+	return fmt.Sprintf(
+		"task: The key of host %q changed, which may be a man-in-the-middle attack:\n  known key:     %s (%s:%d)\n  presented key: %s\nRemove the known key if the change is expected",
+		err.Host,
+		err.KnownFingerprint,
+		err.KnownHostsFile,
+		err.Line,
+		err.Fingerprint,
+	)
+}
+
+func (err *TaskSSHHostKeyChangedError) Code() int {
+	return CodeTaskSSHHostKeyChanged
+}
+
+// TaskPluginExitError is returned when a plugin export returns a non-zero exit
+// code.
+type TaskPluginExitError struct {
+	Plugin   string
+	Export   string
+	ExitCode int
+	// Message is the output of the export, which explains the failure.
+	Message string
+}
+
+func (err *TaskPluginExitError) Error() string {
+	if err.Message != "" {
+		return fmt.Sprintf(`task: Plugin %q failed to call %q: %s`, err.Plugin, err.Export, err.Message)
+	}
+	return fmt.Sprintf(`task: Plugin %q failed to call %q: exit code %d`, err.Plugin, err.Export, err.ExitCode)
+}
+
+func (err *TaskPluginExitError) Code() int {
+	return CodeTaskPluginExitError
+}
+
+func (err *TaskPluginExitError) Unwrap() error {
+	return interp.ExitStatus(err.ExitCode)
+}
+
+// TaskJSError is returned when a JavaScript script exits with a non-zero code,
+// either explicitly or because of an uncaught exception. It unwraps to the exit
+// status of the script so it is handled like a command failure.
+type TaskJSError struct {
+	ExitCode int
+	// Exception is the uncaught exception which made the script fail, if any.
+	Exception string
+	// Stack is the stack trace of the exception, innermost frame first.
+	Stack []string
+}
+
+func (err *TaskJSError) Error() string {
+	if err.Exception == "" {
+		return fmt.Sprintf(`js: exit status %d`, err.ExitCode)
+	}
+	var b strings.Builder
+	b.WriteString("js: ")
+	b.WriteString(err.Exception)
+	for _, frame := range err.Stack {
+		b.WriteString("\n    ")
+		b.WriteString(frame)
+	}
+	return b.String()
+}
+
+func (err *TaskJSError) Code() int {
+	return CodeTaskJSError
+}
+
+func (err *TaskJSError) Unwrap() error {
+	return interp.ExitStatus(err.ExitCode)
+}
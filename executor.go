@@ -10,9 +10,12 @@ import (
 	"github.com/puzpuzpuz/xsync/v3"
 	"github.com/sajari/fuzzy"
 
+	taskJs "github.com/go-task/task/v3/internal/js"
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/output"
 	"github.com/go-task/task/v3/internal/sort"
+	taskSsh "github.com/go-task/task/v3/internal/ssh"
+	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
@@ -71,6 +74,11 @@ type (
 		executionHashes      map[string]context.Context
 		executionHashesMutex sync.Mutex
 		watchedDirs          *xsync.MapOf[string, bool]
+		sshPool              *taskSsh.Pool
+		sshInterruptMutex    sync.Mutex
+		interruptSsh         context.CancelFunc
+		prefixedOutput       output.Output
+		plugins              taskfile.Plugins
 	}
 	TempDir struct {
 		Remote      string
@@ -98,11 +106,22 @@ func NewExecutor(opts ...ExecutorOption) *Executor {
 		mkdirMutexMap:        map[string]*sync.Mutex{},
 		executionHashes:      map[string]context.Context{},
 		executionHashesMutex: sync.Mutex{},
+		sshPool:              taskSsh.NewPool(),
 	}
 	e.Options(opts...)
 	return e
 }
 
+// Close releases what the [Executor] keeps across runs once it is done with
+// them, such as the instances of the plugins of the Taskfile and the idle
+// instances of QuickJS.
+func (e *Executor) Close() {
+	if err := e.plugins.Close(context.Background()); err != nil {
+		e.Logger.VerboseErrf(logger.Yellow, "task: error closing plugins: %v\n", err)
+	}
+	taskJs.ClosePool()
+}
+
 // Options loops through the given [ExecutorOption] functions and applies them
 // to the [Executor].
 func (e *Executor) Options(opts ...ExecutorOption) {
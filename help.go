@@ -9,9 +9,11 @@ import (
 	"strings"
 
 	"github.com/Ladicle/tabwriter"
+	"github.com/dustin/go-humanize"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/go-task/task/v3/internal/editors"
+	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/sort"
@@ -106,6 +108,40 @@ func (e *Executor) ListTasks(o ListOptions) (bool, error) {
 	return true, nil
 }
 
+// ListPlugins prints the plugins of the Taskfile, along with what they are
+// allowed to do and the limits they run with.
+func (e *Executor) ListPlugins() error {
+	if e.Taskfile.Plugins.Len() == 0 {
+		e.Logger.Outf(logger.Yellow, "task: No plugins available\n")
+		return nil
+	}
+	e.Logger.Outf(logger.Default, "task: Plugins of this project:\n")
+
+	w := tabwriter.NewWriter(e.Stdout, 0, 8, 6, ' ', 0)
+	for name, plugin := range e.Taskfile.Plugins.All() {
+		e.Logger.FOutf(w, logger.Yellow, "* ")
+		e.Logger.FOutf(w, logger.Green, name)
+		e.Logger.FOutf(w, logger.Default, ": \t%s", filepathext.TryAbsToRel(plugin.Location))
+		capabilities := "no capabilities"
+		if c := plugin.Capabilities(); len(c) > 0 {
+			capabilities = strings.Join(c, ", ")
+		}
+		e.Logger.FOutf(w, logger.Default, "\t%s", capabilities)
+		var limits []string
+		if plugin.MaxMemory > 0 {
+			limits = append(limits, "max_memory: "+humanize.IBytes(plugin.MaxMemory))
+		}
+		if plugin.Timeout > 0 {
+			limits = append(limits, "timeout: "+plugin.Timeout.String())
+		}
+		if len(limits) > 0 {
+			e.Logger.FOutf(w, logger.Cyan, "\t(%s)", strings.Join(limits, ", "))
+		}
+		_, _ = fmt.Fprint(w, "\n")
+	}
+	return w.Flush()
+}
+
 // ListTaskNames prints only the task names in a Taskfile.
 // Only tasks with a non-empty description are printed if allTasks is false.
 // Otherwise, all task names are printed.
@@ -32,6 +32,9 @@ type RunCommandOptions struct {
 	Stdin     io.Reader
 	Stdout    io.Writer
 	Stderr    io.Writer
+	// JSSandbox is the sandbox of the task.qjs and task.civet commands, which
+	// is the default one of their directory when nil.
+	JSSandbox *taskJs.Sandbox
 }
 
 // RunCommand runs a shell command
@@ -62,7 +65,7 @@ func RunCommand(ctx context.Context, opts *RunCommandOptions) error {
 	r, err := interp.New(
 		interp.Params(params...),
 		interp.Env(expand.ListEnviron(environ...)),
-		interp.ExecHandlers(execHandlers()...),
+		interp.ExecHandlers(execHandlers(opts.JSSandbox)...),
 		interp.OpenHandler(openHandler),
 		interp.StdIO(opts.Stdin, opts.Stdout, opts.Stderr),
 		dirOption(opts.Dir),
@@ -146,56 +149,62 @@ func ExpandFields(s string) ([]string, error) {
 	return expand.Fields(cfg, words...)
 }
 
-func execHandlers() (handlers []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc) {
-	handlers = append(handlers, execJs)
+func execHandlers(sandbox *taskJs.Sandbox) (handlers []func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc) {
+	handlers = append(handlers, execJs(sandbox))
 	if useGoCoreUtils {
 		handlers = append(handlers, coreutils.ExecHandler)
 	}
 	return
 }
 
-func execJs(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
-	return func(ctx context.Context, args []string) error {
-		if !experiments.Interp.Enabled() || (args[0] != "task.qjs" && args[0] != "task.civet") {
-			return next(ctx, args)
-		}
+func execJs(sandbox *taskJs.Sandbox) func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if !experiments.Interp.Enabled() || (args[0] != "task.qjs" && args[0] != "task.civet") {
+				return next(ctx, args)
+			}
 
-		if len(args) <= 2 {
-			return fmt.Errorf("missing args: file")
-		}
+			if len(args) <= 2 {
+				return fmt.Errorf("missing args: file")
+			}
 
-		hc := interp.HandlerCtx(ctx)
+			hc := interp.HandlerCtx(ctx)
 
-		taskJs.Setup()
-		js, err := taskJs.NewJavaScript()
-		if err != nil {
-			return fmt.Errorf("js: uninitialized")
-		}
-		defer js.Close()
-
-		env := map[string]string{}
-		hc.Env.Each(func(name string, v expand.Variable) bool {
-			env[name] = v.String()
-			return true
-		})
-		dialect := "js"
-		if args[0] == "task.civet" {
-			dialect = "civet"
-		}
-		opts := &taskJs.JSEvalFileOptions{
-			File:    filepathext.SmartJoin(hc.Dir, args[1]),
-			Dialect: dialect,
-			Env:     env,
-			Args:    []string{},
-			Stdin:   hc.Stdin,
-			Stdout:  hc.Stdout,
-			Stderr:  hc.Stderr,
-		}
-		if len(args) > 2 {
-			opts.Args = args[2:]
+			sandbox := sandbox
+			if sandbox == nil {
+				sandbox = taskJs.DefaultSandbox(hc.Dir)
+			}
+			js, err := taskJs.Get(sandbox)
+			if err != nil {
+				return fmt.Errorf("js: uninitialized: %w", err)
+			}
+			defer js.Release()
+
+			env := map[string]string{}
+			hc.Env.Each(func(name string, v expand.Variable) bool {
+				env[name] = v.String()
+				return true
+			})
+			dialect := "js"
+			if args[0] == "task.civet" {
+				dialect = "civet"
+			}
+			opts := &taskJs.JSEvalFileOptions{
+				File:    filepathext.SmartJoin(hc.Dir, args[1]),
+				Dialect: dialect,
+				Dir:     hc.Dir,
+				Env:     env,
+				Args:    []string{},
+				Stdin:   hc.Stdin,
+				Stdout:  hc.Stdout,
+				Stderr:  hc.Stderr,
+			}
+			if len(args) > 2 {
+				opts.Args = args[2:]
+			}
+			_, err = js.EvalFile(opts)
+			return err
 		}
-		_, err = js.EvalFile(opts)
-		return err
 	}
 }
 
@@ -0,0 +1,113 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// PluginChecker delegates the checks to an export of a plugin, for methods
+// written as <plugin>.<export>. The export is called with a JSON request
+// naming the check to run along with the resolved sources and generates of the
+// task, and answers with a JSON response:
+//
+//	{"check": "is_up_to_date", "task": "build", "dir": "/src", "dry": false,
+//	 "sources": ["/src/main.go"], "generates": ["/src/bin/app"]}
+//	{"up_to_date": true, "value": "..."}
+//
+// The check is one of is_up_to_date, value and on_error. The value is
+// exposed to the task as {{.PLUGIN}}.
+type PluginChecker struct {
+	method string
+	dry    bool
+	call   func(input []byte) ([]byte, error)
+}
+
+type pluginCheckerRequest struct {
+	Check     string   `json:"check"`
+	Task      string   `json:"task"`
+	Dir       string   `json:"dir"`
+	Dry       bool     `json:"dry"`
+	Sources   []string `json:"sources"`
+	Generates []string `json:"generates"`
+}
+
+type pluginCheckerResponse struct {
+	UpToDate bool `json:"up_to_date"`
+	Value    any  `json:"value"`
+}
+
+// NewPluginChecker returns a checker calling a plugin export through call.
+func NewPluginChecker(method string, dry bool, call func(input []byte) ([]byte, error)) *PluginChecker {
+	return &PluginChecker{
+		method: method,
+		dry:    dry,
+		call:   call,
+	}
+}
+
+func (checker *PluginChecker) IsUpToDate(t *ast.Task) (bool, error) {
+	if len(t.Sources) == 0 {
+		return false, nil
+	}
+	response, err := checker.check("is_up_to_date", t)
+	if err != nil {
+		return false, err
+	}
+	return response.UpToDate, nil
+}
+
+func (checker *PluginChecker) Value(t *ast.Task) (any, error) {
+	response, err := checker.check("value", t)
+	if err != nil {
+		return nil, err
+	}
+	return response.Value, nil
+}
+
+func (checker *PluginChecker) OnError(t *ast.Task) error {
+	if len(t.Sources) == 0 {
+		return nil
+	}
+	_, err := checker.check("on_error", t)
+	return err
+}
+
+func (checker *PluginChecker) Kind() string {
+	return "plugin"
+}
+
+func (checker *PluginChecker) check(check string, t *ast.Task) (*pluginCheckerResponse, error) {
+	sources, err := Globs(t.Dir, t.Sources)
+	if err != nil {
+		return nil, err
+	}
+	generates, err := Globs(t.Dir, t.Generates)
+	if err != nil {
+		return nil, err
+	}
+	input, err := json.Marshal(pluginCheckerRequest{
+		Check:     check,
+		Task:      t.Task,
+		Dir:       t.Dir,
+		Dry:       checker.dry,
+		Sources:   sources,
+		Generates: generates,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := checker.call(input)
+	if err != nil {
+		return nil, err
+	}
+	var response pluginCheckerResponse
+	if len(out) > 0 {
+		if err := json.Unmarshal(out, &response); err != nil {
+			return nil, fmt.Errorf(`task: method "%s" returned an invalid response: %w`, checker.method, err)
+		}
+	}
+	return &response, nil
+}
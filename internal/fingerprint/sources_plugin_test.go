@@ -0,0 +1,55 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestPluginChecker(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), nil, 0o644))
+	task := &ast.Task{
+		Task:    "build",
+		Dir:     dir,
+		Sources: []*ast.Glob{{Glob: "*.go"}},
+	}
+
+	var requests []pluginCheckerRequest
+	checker := NewPluginChecker("go.fingerprint", true, func(input []byte) ([]byte, error) {
+		var request pluginCheckerRequest
+		require.NoError(t, json.Unmarshal(input, &request))
+		requests = append(requests, request)
+		return []byte(`{"up_to_date": true, "value": "abc"}`), nil
+	})
+
+	upToDate, err := checker.IsUpToDate(task)
+	require.NoError(t, err)
+	assert.True(t, upToDate)
+
+	value, err := checker.Value(task)
+	require.NoError(t, err)
+	assert.Equal(t, "abc", value)
+
+	require.NoError(t, checker.OnError(task))
+
+	require.Len(t, requests, 3)
+	for i, check := range []string{"is_up_to_date", "value", "on_error"} {
+		assert.Equal(t, pluginCheckerRequest{
+			Check:     check,
+			Task:      "build",
+			Dir:       dir,
+			Dry:       true,
+			Sources:   []string{filepath.Join(dir, "main.go")},
+			Generates: []string{},
+		}, requests[i])
+	}
+}
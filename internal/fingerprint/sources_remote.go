@@ -0,0 +1,167 @@
+package fingerprint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zeebo/xxh3"
+
+	"github.com/go-task/task/v3/internal/ssh"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// RemoteSourcesChecker checks the sources and generates of a task running on
+// an SSH host. Files are compared using their metadata, read over SFTP, so
+// nothing needs to be downloaded: the checksum method hashes their paths,
+// sizes and modification times, and the timestamp method compares their
+// modification times, all taken from the clock of the host.
+type RemoteSourcesChecker struct {
+	method  string
+	tempDir string
+	dry     bool
+}
+
+func NewRemoteSourcesChecker(method, tempDir string, dry bool) (SourcesCheckable, error) {
+	switch method {
+	case "timestamp", "checksum":
+		return &RemoteSourcesChecker{
+			method:  method,
+			tempDir: tempDir,
+			dry:     dry,
+		}, nil
+	case "none":
+		return NoneChecker{}, nil
+	default:
+		return nil, fmt.Errorf(`task: invalid method "%s"`, method)
+	}
+}
+
+func (checker *RemoteSourcesChecker) IsUpToDate(t *ast.Task) (bool, error) {
+	if len(t.Sources) == 0 {
+		return false, nil
+	}
+
+	sources, err := remoteGlobs(t.SshClient, t.Sources)
+	if err != nil {
+		return false, nil
+	}
+
+	stateFile := checker.stateFilePath(t)
+	data, _ := os.ReadFile(stateFile)
+	oldState := strings.TrimSpace(string(data))
+	newState := checker.state(sources)
+
+	if !checker.dry && oldState != newState {
+		if err := os.MkdirAll(filepath.Dir(stateFile), 0o755); err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(stateFile, []byte(newState+"\n"), 0o644); err != nil {
+			return false, err
+		}
+	}
+
+	var generateMaxTime time.Time
+	for _, g := range t.Generates {
+		if g.Negate {
+			continue
+		}
+		generates, err := t.SshClient.Glob(g.Glob)
+		if err != nil {
+			return false, err
+		}
+		if len(generates) == 0 {
+			return false, nil
+		}
+		for _, generate := range generates {
+			generateMaxTime = maxTime(generateMaxTime, generate.ModTime)
+		}
+	}
+	if checker.method == "timestamp" && len(t.Generates) > 0 && remoteMaxTime(sources).After(generateMaxTime) {
+		return false, nil
+	}
+
+	return oldState == newState, nil
+}
+
+func (checker *RemoteSourcesChecker) Value(t *ast.Task) (any, error) {
+	sources, err := remoteGlobs(t.SshClient, t.Sources)
+	if err != nil {
+		return nil, err
+	}
+	if checker.method == "timestamp" {
+		if len(sources) == 0 {
+			return time.Unix(0, 0), nil
+		}
+		return remoteMaxTime(sources), nil
+	}
+	return checker.state(sources), nil
+}
+
+func (checker *RemoteSourcesChecker) OnError(t *ast.Task) error {
+	if len(t.Sources) == 0 {
+		return nil
+	}
+	return os.Remove(checker.stateFilePath(t))
+}
+
+func (checker *RemoteSourcesChecker) Kind() string {
+	return checker.method
+}
+
+// state returns what is compared between two runs: the checksum of the files
+// metadata or the time of the most recent change.
+func (checker *RemoteSourcesChecker) state(sources []ssh.RemoteFile) string {
+	if checker.method == "timestamp" {
+		return strconv.FormatInt(remoteMaxTime(sources).UnixNano(), 10)
+	}
+	h := xxh3.New()
+	for _, f := range sources {
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d\x00", f.Path, f.Size, f.ModTime.UnixNano())
+	}
+	hash := h.Sum128()
+	return fmt.Sprintf("%x%x", hash.Hi, hash.Lo)
+}
+
+// stateFilePath is keyed by the host as well, since the same task may target
+// different hosts from one run to another.
+func (checker *RemoteSourcesChecker) stateFilePath(t *ast.Task) string {
+	return filepath.Join(checker.tempDir, "remote-"+checker.method, normalizeFilename(t.Name()+"@"+t.SshClient.Addr()))
+}
+
+// remoteGlobs is the remote counterpart of Globs.
+func remoteGlobs(client *ssh.SshClient, globs []*ast.Glob) ([]ssh.RemoteFile, error) {
+	files := make(map[string]*ssh.RemoteFile)
+	for _, g := range globs {
+		matches, err := client.Glob(g.Glob)
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if g.Negate {
+				delete(files, match.Path)
+			} else {
+				files[match.Path] = &match
+			}
+		}
+	}
+
+	result := make([]ssh.RemoteFile, 0, len(files))
+	for _, f := range files {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Path < result[j].Path })
+	return result, nil
+}
+
+func remoteMaxTime(files []ssh.RemoteFile) time.Time {
+	var t time.Time
+	for _, f := range files {
+		t = maxTime(t, f.ModTime)
+	}
+	return t
+}
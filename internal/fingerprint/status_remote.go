@@ -0,0 +1,42 @@
+package fingerprint
+
+import (
+	"context"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/internal/ssh"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// RemoteStatusChecker runs the status commands of a task on its SSH host.
+type RemoteStatusChecker struct {
+	logger *logger.Logger
+}
+
+func NewRemoteStatusChecker(logger *logger.Logger) StatusCheckable {
+	return &RemoteStatusChecker{
+		logger: logger,
+	}
+}
+
+func (checker *RemoteStatusChecker) IsUpToDate(ctx context.Context, t *ast.Task) (bool, error) {
+	for _, s := range t.Status {
+		err := t.SshClient.Run(ctx, &ssh.RunOptions{
+			Commands: []string{s},
+			Env:      env.GetMap(t, false),
+		})
+		if err != nil {
+			// Only a command exiting non-zero means the task is not up-to-date
+			var commandErr *errors.TaskSSHCommandError
+			if !errors.As(err, &commandErr) || commandErr.Err != nil {
+				return false, err
+			}
+			checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited non-zero on %s: %s\n", s, t.SshClient.Addr(), err)
+			return false, nil
+		}
+		checker.logger.VerboseOutf(logger.Yellow, "task: status command %s exited zero on %s\n", s, t.SshClient.Addr())
+	}
+	return true, nil
+}
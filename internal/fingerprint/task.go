@@ -79,14 +79,23 @@ func IsTaskUpToDate(
 		opt(config)
 	}
 
-	// If no status checker was given, set up the default one
+	// If no status checker was given, set up the default one. Tasks running on
+	// an SSH host are checked on the host.
 	if config.statusChecker == nil {
-		config.statusChecker = NewStatusChecker(config.logger)
+		if t.SshClient != nil {
+			config.statusChecker = NewRemoteStatusChecker(config.logger)
+		} else {
+			config.statusChecker = NewStatusChecker(config.logger)
+		}
 	}
 
 	// If no sources checker was given, set up the default one
 	if config.sourcesChecker == nil {
-		config.sourcesChecker, err = NewSourcesChecker(config.method, config.tempDir, config.dry)
+		if t.SshClient != nil {
+			config.sourcesChecker, err = NewRemoteSourcesChecker(config.method, config.tempDir, config.dry)
+		} else {
+			config.sourcesChecker, err = NewSourcesChecker(config.method, config.tempDir, config.dry)
+		}
 		if err != nil {
 			return false, err
 		}
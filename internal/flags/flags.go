@@ -71,6 +71,7 @@ var (
 	Interval            time.Duration
 	Global              bool
 	Experiments         bool
+	Plugins             bool
 	Download            bool
 	Offline             bool
 	ClearCache          bool
@@ -159,6 +160,7 @@ func init() {
 
 	if experiments.Plugins.Enabled() {
 		pflag.DurationVar(&Timeout, "timeout", time.Second*10, "Timeout for loading plugins.")
+		pflag.BoolVar(&Plugins, "plugins", false, "Lists the plugins of the Taskfile and what they are allowed to do.")
 	}
 
 	pflag.Parse()
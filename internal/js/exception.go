@@ -0,0 +1,153 @@
+package js
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// stderrTailSize is how much of the end of the stderr of a script is kept to
+// find its uncaught exception in.
+const stderrTailSize = 8 * 1024
+
+// tailWriter keeps the last bytes written to it.
+type tailWriter struct {
+	b []byte
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	if n := len(w.b); n > stderrTailSize {
+		w.b = append(w.b[:0], w.b[n-stderrTailSize:]...)
+	}
+	return len(p), nil
+}
+
+func (w *tailWriter) Reset()         { w.b = w.b[:0] }
+func (w *tailWriter) String() string { return string(w.b) }
+
+// framePosition matches the position at the end of a frame of a stack trace,
+// as in "at f (file:3:9)" or "at file:3:9".
+var framePosition = regexp.MustCompile(`([^\s()]+):(\d+):(\d+)(\)?)$`)
+
+// evalError turns the result of a call to eval or evalFile into an error.
+//
+// Uncaught exceptions are dumped to stderr by QuickJS before the call returns
+// a non-zero code, so they are read back from the end of stderr. Scripts which
+// exit explicitly or exceed their timeout make the call fail with an exit code
+// instead, and close the instance. The first line of scripts evaluated with a
+// prelude starts after it, so prelude is its length, which is removed from the
+// columns of frames on that line.
+//
+// Civet scripts are compiled to JavaScript as QuickJS loads them, so the
+// positions of their frames are mapped back to the script with the source maps
+// of the compile first.
+func (js *JavaScript) evalError(rc uint32, err error, prelude int, dialect string) error {
+	var exitErr *sys.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded:
+		js.failed = true
+		return fmt.Errorf("js: script exceeded its timeout of %s", js.sandbox.Timeout)
+	case errors.As(err, &exitErr):
+		js.failed = true
+		return &errors.TaskJSError{ExitCode: int(exitErr.ExitCode())}
+	case err != nil && js.memory.Exceeded():
+		js.failed = true
+		return fmt.Errorf("js: script exceeded its max_memory of %s: %w", humanize.IBytes(js.sandbox.MaxMemory), err)
+	case err != nil:
+		js.failed = true
+		return err
+	}
+	if rc == 0 {
+		return nil
+	}
+	exception, stack := parseException(js.tail.String())
+	var maps map[string]*sourceMap
+	if dialect == "civet" && len(stack) > 0 {
+		maps = js.sourceMaps()
+	}
+	for i, frame := range stack {
+		stack[i] = mapFrame(mapSource(frame, maps), prelude)
+	}
+	return &errors.TaskJSError{ExitCode: int(rc), Exception: exception, Stack: stack}
+}
+
+// parseException finds the last exception dumped to stderr, which is its
+// message followed by the frames of its stack trace, each starting with "at".
+// Without a stack trace, the output can't be told apart from the one of the
+// script, so no exception is returned.
+func parseException(stderr string) (exception string, stack []string) {
+	lines := strings.Split(strings.TrimRight(stderr, "\n"), "\n")
+	end := len(lines)
+	for end > 0 && !isFrame(lines[end-1]) {
+		end--
+	}
+	start := end
+	for start > 0 && isFrame(lines[start-1]) {
+		start--
+	}
+	if start == end || start == 0 {
+		return "", nil
+	}
+	for _, line := range lines[start:end] {
+		stack = append(stack, strings.TrimSpace(line))
+	}
+	exception = strings.TrimPrefix(strings.TrimSpace(lines[start-1]), "Uncaught ")
+	return exception, stack
+}
+
+func isFrame(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "at ")
+}
+
+// mapFrame moves the position of a frame on the first line of the script
+// before the prelude. Frames of modules imported from files, whose paths are
+// absolute, are left as is.
+func mapFrame(frame string, prelude int) string {
+	m := framePosition.FindStringSubmatchIndex(frame)
+	if m == nil || prelude == 0 {
+		return frame
+	}
+	file, line, column := frame[m[2]:m[3]], frame[m[4]:m[5]], frame[m[6]:m[7]]
+	if strings.HasPrefix(file, "/") || line != "1" {
+		return frame
+	}
+	col, err := strconv.Atoi(column)
+	if err != nil || col <= prelude {
+		return frame
+	}
+	return frame[:m[6]] + strconv.Itoa(col-prelude) + frame[m[7]:]
+}
+
+// mapSource moves the position of a frame from the JavaScript a Civet module
+// was compiled to back to the module, with the source map of the module.
+// Frames of modules without one are left as is.
+func mapSource(frame string, maps map[string]*sourceMap) string {
+	m := framePosition.FindStringSubmatchIndex(frame)
+	if m == nil {
+		return frame
+	}
+	sourceMap := maps[frame[m[2]:m[3]]]
+	if sourceMap == nil {
+		return frame
+	}
+	line, err := strconv.Atoi(frame[m[4]:m[5]])
+	if err != nil {
+		return frame
+	}
+	column, err := strconv.Atoi(frame[m[6]:m[7]])
+	if err != nil {
+		return frame
+	}
+	line, column, ok := sourceMap.position(line, column)
+	if !ok {
+		return frame
+	}
+	return frame[:m[4]] + strconv.Itoa(line) + ":" + strconv.Itoa(column) + frame[m[7]:]
+}
@@ -0,0 +1,139 @@
+package js
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+func TestParseException(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		stderr    string
+		exception string
+		stack     []string
+	}{
+		{
+			name: "error",
+			stderr: "working\n" +
+				"Error: boom\n" +
+				"    at fail (<input>:3:11)\n" +
+				"    at <eval> (<input>:5:1)\n",
+			exception: "Error: boom",
+			stack:     []string{"at fail (<input>:3:11)", "at <eval> (<input>:5:1)"},
+		},
+		{
+			name:      "uncaught",
+			stderr:    "Uncaught TypeError: not a function\n    at <eval> (<input>:1:40)\n",
+			exception: "TypeError: not a function",
+			stack:     []string{"at <eval> (<input>:1:40)"},
+		},
+		{
+			name:   "no stack trace",
+			stderr: "boom\n",
+		},
+		{
+			name:   "only frames",
+			stderr: "    at <eval> (<input>:1:1)\n",
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			exception, stack := parseException(test.stderr)
+			assert.Equal(t, test.exception, exception)
+			assert.Equal(t, test.stack, stack)
+		})
+	}
+}
+
+func TestMapFrame(t *testing.T) {
+	t.Parallel()
+
+	prelude := len(taskPrelude)
+	assert.Equal(t, "at <eval> (<input>:1:7)", mapFrame("at <eval> (<input>:1:"+strconv.Itoa(prelude+7)+")", prelude))
+	assert.Equal(t, "at <input>:1:7", mapFrame("at <input>:1:"+strconv.Itoa(prelude+7), prelude))
+	assert.Equal(t, "at <eval> (<input>:2:7)", mapFrame("at <eval> (<input>:2:7)", prelude))
+	assert.Equal(t, "at f (/dev/task/task.js:1:60)", mapFrame("at f (/dev/task/task.js:1:60)", prelude))
+	assert.Equal(t, "at <eval> (<input>:1:60)", mapFrame("at <eval> (<input>:1:60)", 0))
+	assert.Equal(t, "at native", mapFrame("at native", prelude))
+}
+
+func TestEvalError(t *testing.T) {
+	t.Parallel()
+
+	prelude := len(taskPrelude)
+	js := &JavaScript{}
+	_, _ = js.tail.Write([]byte("Error: boom\n    at <eval> (<input>:1:" + strconv.Itoa(prelude+7) + ")\n"))
+
+	var jsErr *errors.TaskJSError
+	require.ErrorAs(t, js.evalError(1, nil, prelude, "js"), &jsErr)
+	assert.Equal(t, "Error: boom", jsErr.Exception)
+	assert.Equal(t, []string{"at <eval> (<input>:1:7)"}, jsErr.Stack)
+}
+
+func TestMapSource(t *testing.T) {
+	t.Parallel()
+
+	// The second line of the JavaScript is the third one of the source, and
+	// its seventh column the seventh one there
+	compiled, err := parseSourceMap([]byte(`{"version":3,"sources":["<input>"],"mappings":"AAAA;AAEA,MAAM"}`))
+	require.NoError(t, err)
+	maps := map[string]*sourceMap{"<input>": compiled}
+
+	assert.Equal(t, "at <eval> (<input>:3:7)", mapSource("at <eval> (<input>:2:9)", maps))
+	assert.Equal(t, "at <input>:3:1", mapSource("at <input>:2:3", maps))
+	assert.Equal(t, "at <eval> (<input>:1:1)", mapSource("at <eval> (<input>:1:5)", maps))
+	assert.Equal(t, "at <eval> (<input>:4:1)", mapSource("at <eval> (<input>:4:1)", maps))
+	assert.Equal(t, "at f (/dev/task/task.js:2:9)", mapSource("at f (/dev/task/task.js:2:9)", maps))
+	assert.Equal(t, "at <eval> (<input>:2:9)", mapSource("at <eval> (<input>:2:9)", nil))
+	assert.Equal(t, "at native", mapSource("at native", maps))
+}
+
+func TestParseSourceMap(t *testing.T) {
+	t.Parallel()
+
+	values, err := decodeVLQ("AgBD")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 16, -1}, values)
+
+	_, err = decodeVLQ("g")
+	assert.Error(t, err)
+	_, err = decodeVLQ("A!")
+	assert.Error(t, err)
+	_, err = parseSourceMap([]byte(`{"version":2,"mappings":"AAAA"}`))
+	assert.Error(t, err)
+
+	// Segments without a source map nothing, but still move the column
+	compiled, err := parseSourceMap([]byte(`{"version":3,"mappings":";E,EAEE"}`))
+	require.NoError(t, err)
+	line, column, ok := compiled.position(2, 5)
+	assert.True(t, ok)
+	assert.Equal(t, 3, line)
+	assert.Equal(t, 3, column)
+	_, _, ok = compiled.position(1, 1)
+	assert.False(t, ok)
+	_, _, ok = compiled.position(2, 1)
+	assert.False(t, ok)
+}
+
+func TestTailWriter(t *testing.T) {
+	t.Parallel()
+
+	var w tailWriter
+	_, _ = w.Write([]byte(strings.Repeat("a", stderrTailSize)))
+	_, _ = w.Write([]byte("Error: boom\n"))
+	assert.Len(t, w.String(), stderrTailSize)
+	assert.True(t, strings.HasSuffix(w.String(), "aError: boom\n"))
+}
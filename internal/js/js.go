@@ -12,35 +12,29 @@ import (
 	"path/filepath"
 	"slices"
 	"sync"
+	"sync/atomic"
 
+	"github.com/dustin/go-humanize"
 	extism "github.com/extism/go-sdk"
 	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/experimental"
+
+	"github.com/go-task/task/v3/internal/wasmext"
 )
 
 //go:embed qjs.wasm
 var qjswasm []byte
 
 var (
-	ctx            context.Context
-	cache          wazero.CompilationCache
-	compiledPlugin *extism.CompiledPlugin
-	once           sync.Once
+	ctx   context.Context
+	cache wazero.CompilationCache
+	once  sync.Once
 )
 
 func setup() {
 	ctx = context.Background()
 
 	cache = wazero.NewCompilationCache()
-
-	mft := extism.Manifest{
-		Wasm:   []extism.Wasm{extism.WasmData{Data: qjswasm}},
-		Config: map[string]string{},
-	}
-	config := extism.PluginConfig{
-		EnableWasi:    true,
-		RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(cache),
-	}
-	compiledPlugin, _ = extism.NewCompiledPlugin(ctx, mft, config, []extism.HostFunction{})
 }
 
 func Setup() {
@@ -48,23 +42,66 @@ func Setup() {
 }
 
 type JavaScript struct {
-	plugin *extism.Plugin
-	stdin  *bytes.Buffer
-	stdout *bytes.Buffer
-	stderr *bytes.Buffer
+	plugin  *extism.Plugin
+	sandbox *Sandbox
+	// key is the one of the sandbox, which the pool keeps the instance under.
+	key    string
+	fs     *taskFS
+	memory *wasmext.MaxMemory
+	stdin  *streamReader
+	stdout *streamWriter
+	stderr *streamWriter
+	// output is the stdout of the scripts evaluated without one.
+	output bytes.Buffer
+	// tail is the end of the stderr of the script, where uncaught exceptions
+	// are found.
+	tail tailWriter
+	// failed is set once a call to the plugin failed, after which the
+	// instance isn't reused.
+	failed bool
+	// exited is set once QuickJS is closed, which a script exiting with code
+	// 0 does without making the call fail.
+	exited *atomic.Bool
+	// closed is set once Close gave back the slot of the instance.
+	closed atomic.Bool
 }
 
-func NewJavaScript() (*JavaScript, error) {
-	if compiledPlugin == nil {
-		return nil, fmt.Errorf("js: init failed")
+// NewJavaScript instantiates QuickJS in the sandbox, or in the default one of
+// the working directory when it is nil. Once maxLive instances are open, it
+// waits for one to be closed.
+func NewJavaScript(sandbox *Sandbox) (*JavaScript, error) {
+	acquire()
+	return instantiate(sandbox)
+}
+
+// instantiate is NewJavaScript in a slot the caller acquired, which it gives
+// back on failure.
+func instantiate(sandbox *Sandbox) (_ *JavaScript, err error) {
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+	Setup()
+	if sandbox == nil {
+		sandbox = DefaultSandbox("")
+	}
+	compiledPlugin, err := sandbox.compile()
+	if err != nil {
+		return nil, fmt.Errorf("js: init failed: %w", err)
 	}
-	var stdin bytes.Buffer
-	var stdout bytes.Buffer
-	var stderr bytes.Buffer
-	plugin, err := compiledPlugin.Instance(ctx, extism.PluginInstanceConfig{
+	var stdin streamReader
+	var stdout, stderr streamWriter
+	fs := &taskFS{}
+	memory := wasmext.NewMaxMemory(sandbox.MaxMemory)
+	exited := &atomic.Bool{}
+	instanceCtx := experimental.WithCloseNotifier(memory.WithContext(ctx), experimental.CloseNotifyFunc(func(context.Context, uint32) {
+		exited.Store(true)
+	}))
+	plugin, err := compiledPlugin.Instance(instanceCtx, extism.PluginInstanceConfig{
 		ModuleConfig: wazero.NewModuleConfig().
 			WithRandSource(rand.Reader).
-			WithFSConfig(wazero.NewFSConfig().WithDirMount("/", "/")).
+			WithFSConfig(sandbox.fsConfig(fs)).
 			WithSysNanosleep().
 			WithSysNanotime().
 			WithSysWalltime().
@@ -75,24 +112,127 @@ func NewJavaScript() (*JavaScript, error) {
 	if err != nil {
 		return nil, err
 	}
+	if memory.Exceeded() {
+		plugin.Close(ctx)
+		return nil, fmt.Errorf("js: init failed: the memory of QuickJS exceeds the max_memory of %s", humanize.IBytes(sandbox.MaxMemory))
+	}
 	_, _, err = plugin.Call("warmup", nil)
 	if err != nil {
 		plugin.Close(ctx)
 		return nil, err
 	}
 
-	return &JavaScript{
-		plugin: plugin,
-		stdin:  &stdin,
-		stdout: &stdout,
-		stderr: &stderr,
-	}, nil
+	js := &JavaScript{
+		plugin:  plugin,
+		sandbox: sandbox,
+		key:     sandbox.key(),
+		fs:      fs,
+		memory:  memory,
+		stdin:   &stdin,
+		stdout:  &stdout,
+		stderr:  &stderr,
+		exited:  exited,
+	}
+	if err := js.evalInternal(realmSnapshot); err != nil {
+		plugin.Close(ctx)
+		return nil, fmt.Errorf("js: init failed: %w", err)
+	}
+	return js, nil
 }
 
+// Close closes the instance and gives back its slot in the pool. Closing it
+// again does nothing.
 func (js *JavaScript) Close() {
-	js.stdout.Reset()
-	js.stderr.Reset()
+	if !js.closed.CompareAndSwap(false, true) {
+		return
+	}
+	js.output.Reset()
 	js.plugin.Close(ctx)
+	release()
+}
+
+// connect streams the stdio of the instance from and to the ones of the script
+// it evaluates. The stdout of a script without one is kept in output.
+func (js *JavaScript) connect(stdin io.Reader, stdout, stderr io.Writer) {
+	js.output.Reset()
+	js.tail.Reset()
+	if stdout == nil {
+		stdout = &js.output
+	}
+	if stderr == nil {
+		stderr = io.Discard
+	}
+	js.stdin.r, js.stdout.w, js.stderr.w = stdin, stdout, io.MultiWriter(stderr, &js.tail)
+}
+
+// reset clears what an evaluation left in the instance, and restores the
+// globals of its realm.
+func (js *JavaScript) reset() error {
+	js.output.Reset()
+	js.tail.Reset()
+	js.stdin.r, js.stdout.w, js.stderr.w = nil, nil, nil
+	return js.evalInternal(realmRestore)
+}
+
+// evalInternal evaluates one of the scripts Task runs in the instance for
+// itself, without the task global answering its calls.
+func (js *JavaScript) evalInternal(script string) error {
+	js.fs.reset(nil, nil)
+	js.plugin.Config = map[string]string{"eval.dir": "/"}
+	defer func() { js.plugin.Config = map[string]string{} }()
+	rc, _, err := js.plugin.Call("eval", []byte(script))
+	switch {
+	case err != nil:
+		return err
+	case rc != 0:
+		return fmt.Errorf("js: internal script failed with code %d", rc)
+	case js.exited.Load():
+		return fmt.Errorf("js: QuickJS exited")
+	}
+	return nil
+}
+
+// call calls an export of QuickJS within the timeout of the sandbox.
+func (js *JavaScript) call(name string, data []byte) (uint32, []byte, error) {
+	js.memory.Reset()
+	if js.sandbox.Timeout <= 0 {
+		return js.plugin.Call(name, data)
+	}
+	callCtx, cancel := context.WithTimeout(ctx, js.sandbox.Timeout)
+	defer cancel()
+	return js.plugin.CallWithContext(callCtx, name, data)
+}
+
+// sourceMaps returns the source maps of the Civet modules the last evaluation
+// compiled, by module name. None are returned when QuickJS has none to give.
+func (js *JavaScript) sourceMaps() map[string]*sourceMap {
+	rc, out, err := js.plugin.Call("sourceMaps", nil)
+	if err != nil || rc != 0 {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil
+	}
+	maps := make(map[string]*sourceMap, len(raw))
+	for name, data := range raw {
+		if sourceMap, err := parseSourceMap(data); err == nil {
+			maps[name] = sourceMap
+		}
+	}
+	return maps
+}
+
+// setEnv replaces the environment of the scripts, so that the one of a
+// previous evaluation doesn't leak into the next. Sandboxes hiding the
+// environment get an empty one.
+func (js *JavaScript) setEnv(env map[string]string) {
+	if env == nil || js.sandbox.HideEnv {
+		env = map[string]string{}
+	}
+	if envJson, err := json.Marshal(env); err == nil {
+		_, _, _ = js.plugin.Call("setEnv", []byte(envJson))
+	}
 }
 
 type JSEvalOptions struct {
@@ -100,31 +240,27 @@ type JSEvalOptions struct {
 	Dialect string
 	Dir     string
 	Env     map[string]string
-	Stdin   io.Reader
-	Stdout  io.Writer
-	Stderr  io.Writer
+	// Vars are the variables scripts read from task.vars.
+	Vars map[string]any
+	// Host is what the other functions of the task global call. They fail
+	// when it is nil.
+	Host   Host
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
 }
 
+// Eval evaluates a script as a module, with the task global defined. Its
+// stdio is streamed as the script runs, and its stdout is returned when the
+// options have no Stdout. Scripts which fail return an *errors.TaskJSError.
 func (js *JavaScript) Eval(options *JSEvalOptions) (string, error) {
 	if options == nil {
 		return "", fmt.Errorf("js: nil options given")
 	}
 
-	if js.stdin != nil {
-		js.stdin.Reset()
-	}
-	if js.stdout != nil {
-		js.stdout.Reset()
-	}
-	if js.stderr != nil {
-		js.stderr.Reset()
-	}
+	js.connect(options.Stdin, options.Stdout, options.Stderr)
 
-	if options.Env != nil {
-		if envJson, err := json.Marshal(options.Env); err == nil {
-			_, _, _ = js.plugin.Call("setEnv", []byte(envJson))
-		}
-	}
+	js.setEnv(options.Env)
 
 	dir, _ := os.Getwd()
 	if len(options.Dir) != 0 {
@@ -134,25 +270,14 @@ func (js *JavaScript) Eval(options *JSEvalOptions) (string, error) {
 
 	js.plugin.Config["eval.dialect"] = options.Dialect
 
-	if options.Stdin != nil {
-		_, _ = options.Stdin.Read(js.stdin.Bytes())
-	}
+	js.fs.reset(options.Vars, options.Host)
 
-	exit, _, err := js.plugin.Call("eval", []byte(options.Script))
-	if err != nil {
+	rc, _, err := js.call("eval", []byte(taskPrelude+options.Script))
+	if err = js.evalError(rc, err, len(taskPrelude), options.Dialect); err != nil {
 		return "", err
 	}
-	if exit > 0 {
-		return "", fmt.Errorf("js: unknown error, exit with code %d", exit)
-	}
-	if options.Stdout != nil {
-		_, _ = options.Stdout.Write(js.stdout.Bytes())
-	}
-	if options.Stderr != nil {
-		_, _ = options.Stderr.Write(js.stderr.Bytes())
-	}
 	js.plugin.Config = map[string]string{}
-	return js.stdout.String(), nil
+	return js.output.String(), nil
 }
 
 type JSEvalFileOptions struct {
@@ -166,26 +291,17 @@ type JSEvalFileOptions struct {
 	Stderr  io.Writer
 }
 
+// EvalFile evaluates a script file, with its stdio handled like Eval does. The
+// task global is defined for it too, but without any variables, and its
+// functions fail.
 func (js *JavaScript) EvalFile(options *JSEvalFileOptions) (string, error) {
 	if options == nil {
 		return "", fmt.Errorf("js: nil options given")
 	}
 
-	if js.stdin != nil {
-		js.stdin.Reset()
-	}
-	if js.stdout != nil {
-		js.stdout.Reset()
-	}
-	if js.stderr != nil {
-		js.stderr.Reset()
-	}
+	js.connect(options.Stdin, options.Stdout, options.Stderr)
 
-	if options.Env != nil {
-		if envJson, err := json.Marshal(options.Env); err == nil {
-			_, _, _ = js.plugin.Call("setEnv", []byte(envJson))
-		}
-	}
+	js.setEnv(options.Env)
 
 	dir, _ := os.Getwd()
 	if len(options.Dir) != 0 {
@@ -203,23 +319,10 @@ func (js *JavaScript) EvalFile(options *JSEvalFileOptions) (string, error) {
 
 	js.plugin.Config["evalFile.dialect"] = options.Dialect
 
-	if options.Stdin != nil {
-		_, _ = options.Stdin.Read(js.stdin.Bytes())
-	}
-
-	exit, _, err := js.plugin.Call("evalFile", []byte(options.File))
-	if err != nil {
+	rc, _, err := js.call("evalFile", []byte(options.File))
+	if err = js.evalError(rc, err, 0, options.Dialect); err != nil {
 		return "", err
 	}
-	if exit > 0 {
-		return "", fmt.Errorf("js: unknown error, exit with code %d", exit)
-	}
-	if options.Stdout != nil {
-		_, _ = options.Stdout.Write(js.stdout.Bytes())
-	}
-	if options.Stderr != nil {
-		_, _ = options.Stderr.Write(js.stderr.Bytes())
-	}
 	js.plugin.Config = map[string]string{}
-	return js.stdout.String(), nil
+	return js.output.String(), nil
 }
@@ -1,19 +1,22 @@
 package js
 
 import (
+	"bytes"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/go-task/task/v3/errors"
 )
 
 func TestBasic(t *testing.T) {
 	t.Parallel()
 
-	Setup()
-	js1, err := NewJavaScript()
+	js1, err := NewJavaScript(nil)
 	require.NoError(t, err)
 	defer js1.Close()
 
@@ -23,7 +26,7 @@ func TestBasic(t *testing.T) {
 	require.NoError(t, err)
 	assert.Contains(t, out, "hello, javascript")
 
-	js2, err := NewJavaScript()
+	js2, err := NewJavaScript(nil)
 	require.NoError(t, err)
 	defer js2.Close()
 
@@ -35,11 +38,34 @@ func TestBasic(t *testing.T) {
 	assert.Contains(t, out, "hello, civet")
 }
 
+func TestCivetException(t *testing.T) {
+	t.Parallel()
+
+	js, err := NewJavaScript(nil)
+	require.NoError(t, err)
+	defer js.Close()
+
+	_, err = js.Eval(&JSEvalOptions{
+		Script: "greet := (name: string) =>\n" +
+			"  name |> print\n" +
+			"  throw new Error `no ${name}`\n" +
+			"greet \"civet\"\n",
+		Dialect: "civet",
+	})
+	var jsErr *errors.TaskJSError
+	require.ErrorAs(t, err, &jsErr)
+	assert.Equal(t, "Error: no civet", jsErr.Exception)
+	require.NotEmpty(t, jsErr.Stack)
+	// The positions are the ones of the Civet script, not of the JavaScript
+	// it was compiled to
+	assert.Regexp(t, `<input>:3:\d+\)?$`, jsErr.Stack[0])
+	assert.Regexp(t, `<input>:4:\d+\)?$`, jsErr.Stack[len(jsErr.Stack)-1])
+}
+
 func TestCwd(t *testing.T) {
 	t.Parallel()
 
-	Setup()
-	js, err := NewJavaScript()
+	js, err := NewJavaScript(nil)
 	require.NoError(t, err)
 	defer js.Close()
 
@@ -64,3 +90,148 @@ func TestCwd(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, cwd, strings.TrimSuffix(out, "\n"))
 }
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	js1, err := Get(nil)
+	require.NoError(t, err)
+
+	out, err := js1.Eval(&JSEvalOptions{
+		Script: `print(process.env.NAME, task.vars.NAME)`,
+		Env:    map[string]string{"NAME": "env"},
+		Vars:   map[string]any{"NAME": "var"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "env var\n", out)
+	js1.Release()
+
+	js2, err := Get(nil)
+	require.NoError(t, err)
+	defer js2.Release()
+
+	out, err = js2.Eval(&JSEvalOptions{
+		Script: `print(process.env.NAME, task.vars.NAME)`,
+		Vars:   map[string]any{"NAME": "other"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "undefined other\n", out)
+}
+
+func TestRealm(t *testing.T) {
+	t.Parallel()
+
+	js, err := NewJavaScript(nil)
+	require.NoError(t, err)
+	defer js.Close()
+
+	_, err = js.Eval(&JSEvalOptions{
+		Script: `globalThis.x = 1; globalThis.print = () => {};`,
+	})
+	require.NoError(t, err)
+	require.NoError(t, js.reset())
+
+	out, err := js.Eval(&JSEvalOptions{
+		Script: `print(typeof globalThis.x, typeof task.run)`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "undefined function\n", out)
+}
+
+func TestExit(t *testing.T) {
+	t.Parallel()
+
+	js, err := NewJavaScript(nil)
+	require.NoError(t, err)
+	defer js.Close()
+
+	_, err = js.Eval(&JSEvalOptions{
+		Script: `import * as std from "qjs:std"; print("before"); std.exit(0);`,
+	})
+	require.NoError(t, err)
+	assert.True(t, js.exited.Load())
+	assert.Error(t, js.reset())
+}
+
+func TestPoolMaxIdle(t *testing.T) {
+	t.Parallel()
+
+	instances := make([]*JavaScript, maxIdle()+1)
+	for i := range instances {
+		js, err := NewJavaScript(nil)
+		require.NoError(t, err)
+		instances[i] = js
+	}
+	for _, js := range instances {
+		js.Release()
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	assert.LessOrEqual(t, len(pool.idle), maxIdle())
+}
+
+func TestPoolMaxLive(t *testing.T) {
+	// Not parallel, so that no other test holds instances meanwhile
+	instances := make([]*JavaScript, maxLive())
+	for i := range instances {
+		js, err := Get(nil)
+		require.NoError(t, err)
+		instances[i] = js
+	}
+
+	got := make(chan *JavaScript)
+	go func() {
+		js, err := Get(nil)
+		assert.NoError(t, err)
+		got <- js
+	}()
+	select {
+	case <-got:
+		t.Fatal("Get didn't wait for an instance to be released")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	instances[0].Release()
+	select {
+	case js := <-got:
+		require.NotNil(t, js)
+		instances[0] = js
+	case <-time.After(10 * time.Second):
+		t.Fatal("Get didn't return the released instance")
+	}
+	for _, js := range instances {
+		js.Release()
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	assert.LessOrEqual(t, pool.live, maxLive())
+}
+
+func TestStreaming(t *testing.T) {
+	t.Parallel()
+
+	js, err := Get(nil)
+	require.NoError(t, err)
+	defer js.Release()
+
+	// Larger than the buffers of the guest, so that it reads stdin in several calls
+	input := strings.Repeat("x", 4*1024*1024)
+	var stdout, stderr bytes.Buffer
+	out, err := js.Eval(&JSEvalOptions{
+		Script: `
+		import * as std from "qjs:std";
+		const input = std.in.readAsString();
+		std.out.puts(input.toUpperCase());
+		std.err.puts(String(input.length));
+		`,
+		Stdin:  strings.NewReader(input),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	assert.Equal(t, strings.ToUpper(input), stdout.String())
+	assert.Equal(t, "4194304", stderr.String())
+}
@@ -0,0 +1,161 @@
+package js
+
+import (
+	"runtime"
+	"sync"
+)
+
+// pool keeps the idle instances of QuickJS, so that scripts don't each pay for
+// the instantiation and warmup of one. Instances are reused by the scripts of
+// the same sandbox, and concurrent scripts each get an instance of their own.
+// The idle instances are ordered from the least to the most recently released.
+// At most maxLive instances are open at once, idle or in use, and scripts wait
+// for one to be closed or released beyond that.
+var pool = struct {
+	mutex sync.Mutex
+	idle  []*JavaScript
+	// live is how many instances are open, including the idle ones.
+	live int
+}{}
+
+// freed is signalled whenever an instance is closed or released.
+var freed = sync.NewCond(&pool.mutex)
+
+// maxIdle is how many idle instances the pool keeps at most, whatever their
+// sandboxes.
+func maxIdle() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// maxLive is how many instances are open at most. It leaves room above maxIdle
+// for the scripts waiting on commands or files rather than running.
+func maxLive() int {
+	return 2 * runtime.GOMAXPROCS(0)
+}
+
+// acquire takes the slot of a new instance. Once maxLive instances are open,
+// idle ones are closed to make room, and it waits for one to be closed when
+// they are all in use.
+func acquire() {
+	pool.mutex.Lock()
+	for pool.live >= maxLive() {
+		if !evictLocked() {
+			freed.Wait()
+		}
+	}
+	pool.live++
+	pool.mutex.Unlock()
+}
+
+// release gives back the slot of a closed instance.
+func release() {
+	pool.mutex.Lock()
+	pool.live--
+	pool.mutex.Unlock()
+	freed.Broadcast()
+}
+
+// yield gives back the slot of an instance while it waits for fn, so that the
+// scripts of the tasks fn runs don't wait for it.
+func yield(fn func()) {
+	release()
+	defer acquire()
+	fn()
+}
+
+// evictLocked closes the least recently released idle instance, if any. The
+// mutex of the pool is held by the caller, and is released while closing it.
+func evictLocked() bool {
+	if len(pool.idle) == 0 {
+		return false
+	}
+	evicted := pool.idle[0]
+	pool.idle = pool.idle[1:]
+	pool.mutex.Unlock()
+	evicted.Close()
+	pool.mutex.Lock()
+	return true
+}
+
+// Get returns an idle instance of QuickJS in the sandbox, or a new one when
+// there is none. A nil sandbox is the default one of the working directory.
+// Once maxLive instances are in use, it waits for one to be released or
+// closed. Callers must give it back with Release once done.
+func Get(sandbox *Sandbox) (*JavaScript, error) {
+	if sandbox == nil {
+		sandbox = DefaultSandbox("")
+	}
+	key := sandbox.key()
+	pool.mutex.Lock()
+	for {
+		if js := takeIdleLocked(key); js != nil {
+			pool.mutex.Unlock()
+			// The timeout and the environment may differ between sandboxes
+			// sharing their instances
+			js.sandbox = sandbox
+			return js, nil
+		}
+		if pool.live < maxLive() {
+			break
+		}
+		if !evictLocked() {
+			freed.Wait()
+		}
+	}
+	pool.live++
+	pool.mutex.Unlock()
+	return instantiate(sandbox)
+}
+
+// takeIdleLocked removes the most recently released idle instance with the key
+// from the pool. The mutex of the pool is held by the caller.
+func takeIdleLocked(key string) *JavaScript {
+	for i := len(pool.idle) - 1; i >= 0; i-- {
+		js := pool.idle[i]
+		if js.key != key {
+			continue
+		}
+		pool.idle = append(pool.idle[:i], pool.idle[i+1:]...)
+		return js
+	}
+	return nil
+}
+
+// Release resets the instance and returns it to the pool. Once the pool is
+// full, the least recently released instance is closed to make room for it.
+// Instances that failed to evaluate a script or that scripts exited are
+// closed, as they may be left in any state.
+func (js *JavaScript) Release() {
+	if js.failed || js.exited.Load() {
+		js.Close()
+		return
+	}
+	if err := js.reset(); err != nil {
+		js.Close()
+		return
+	}
+	pool.mutex.Lock()
+	pool.idle = append(pool.idle, js)
+	var evicted *JavaScript
+	if len(pool.idle) > maxIdle() {
+		evicted = pool.idle[0]
+		pool.idle = pool.idle[1:]
+	}
+	pool.mutex.Unlock()
+	freed.Broadcast()
+	if evicted != nil {
+		evicted.Close()
+	}
+}
+
+// ClosePool closes the idle instances of the pool. Instances in use are still
+// returned to it once released.
+func ClosePool() {
+	pool.mutex.Lock()
+	idle := pool.idle
+	pool.idle = nil
+	pool.mutex.Unlock()
+	for _, js := range idle {
+		js.Close()
+	}
+}
@@ -0,0 +1,19 @@
+// The globals of the realm as they are before any script runs. An instance is
+// reused for the scripts of several commands, so the globals a script adds or
+// replaces are restored before the next one runs. Changes made to the objects
+// of the realm themselves, like their prototypes, are not. Globals which can't
+// be restored make restore throw, and the instance isn't reused.
+const globals = new Map(
+  Reflect.ownKeys(globalThis).map((key) => [key, Object.getOwnPropertyDescriptor(globalThis, key)]),
+);
+
+export function restore() {
+  for (const key of Reflect.ownKeys(globalThis)) {
+    if (!globals.has(key)) {
+      delete globalThis[key];
+    }
+  }
+  for (const [key, descriptor] of globals) {
+    Object.defineProperty(globalThis, key, descriptor);
+  }
+}
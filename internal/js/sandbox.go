@@ -0,0 +1,154 @@
+package js
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	extism "github.com/extism/go-sdk"
+	"github.com/tetratelabs/wazero"
+)
+
+// Sandbox is what scripts can reach of the host.
+type Sandbox struct {
+	// Mounts maps the directories of the host scripts can reach to the paths
+	// they see them at.
+	Mounts map[string]string
+	// ReadOnly prevents scripts from writing to the mounts.
+	ReadOnly bool
+	// MaxMemory caps the memory of the interpreter, in bytes.
+	MaxMemory uint64
+	// Network allows scripts to send HTTP requests.
+	Network bool
+	// Timeout caps how long a single script can run.
+	Timeout time.Duration
+	// HideEnv hides the environment from scripts.
+	HideEnv bool
+	// Exec allows scripts to run shell commands with task.exec.
+	Exec bool
+	// Run allows scripts to run tasks with task.run.
+	Run bool
+}
+
+// DefaultSandbox is the sandbox of scripts run in a directory, which mounts
+// it and the temporary directory at their own paths, and lets scripts run
+// commands and tasks.
+func DefaultSandbox(dir string) *Sandbox {
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+	mounts := map[string]string{}
+	for _, dir := range []string{dir, os.TempDir()} {
+		if dir != "" {
+			mounts[dir] = filepath.ToSlash(dir)
+		}
+	}
+	return &Sandbox{Mounts: mounts, Exec: true, Run: true}
+}
+
+// Restrict returns the sandbox narrowed to what the other one allows. Only the
+// mounts within the ones of the other sandbox are kept, and limits which are
+// set in both are the lowest of the two.
+func (s *Sandbox) Restrict(other *Sandbox) *Sandbox {
+	restricted := &Sandbox{
+		Mounts:    map[string]string{},
+		ReadOnly:  s.ReadOnly || other.ReadOnly,
+		MaxMemory: lowestLimit(s.MaxMemory, other.MaxMemory),
+		Network:   s.Network && other.Network,
+		Timeout:   lowestLimit(s.Timeout, other.Timeout),
+		HideEnv:   s.HideEnv || other.HideEnv,
+		Exec:      s.Exec && other.Exec,
+		Run:       s.Run && other.Run,
+	}
+	for host, guest := range s.Mounts {
+		if other.mounts(host) {
+			restricted.Mounts[host] = guest
+		}
+	}
+	return restricted
+}
+
+// mounts reports whether the directory of the host is within one of the
+// mounts of the sandbox.
+func (s *Sandbox) mounts(dir string) bool {
+	for host := range s.Mounts {
+		rel, err := filepath.Rel(host, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowestLimit returns the lowest of two limits, where 0 is no limit.
+func lowestLimit[T uint64 | time.Duration](a, b T) T {
+	if a == 0 || b == 0 {
+		return max(a, b)
+	}
+	return min(a, b)
+}
+
+// key identifies the instances of QuickJS the sandbox can reuse. The timeout,
+// the environment and what scripts can call Task for are applied to each
+// evaluation, so they aren't part of it.
+func (s *Sandbox) key() string {
+	var b strings.Builder
+	for _, host := range slices.Sorted(maps.Keys(s.Mounts)) {
+		fmt.Fprintf(&b, "%q:%q,", host, s.Mounts[host])
+	}
+	fmt.Fprintf(&b, "read_only=%t,max_memory=%d,network=%t", s.ReadOnly, s.MaxMemory, s.Network)
+	return b.String()
+}
+
+// fsConfig mounts the directories of the sandbox, and the files scripts reach
+// Task through.
+func (s *Sandbox) fsConfig(fs *taskFS) wazero.FSConfig {
+	config := wazero.NewFSConfig()
+	for _, host := range slices.Sorted(maps.Keys(s.Mounts)) {
+		if s.ReadOnly {
+			config = config.WithReadOnlyDirMount(host, s.Mounts[host])
+		} else {
+			config = config.WithDirMount(host, s.Mounts[host])
+		}
+	}
+	return config.WithFSMount(fs, taskDir)
+}
+
+// compiled keeps QuickJS compiled with the network allowed or not.
+var compiled = struct {
+	mutex   sync.Mutex
+	plugins map[bool]*extism.CompiledPlugin
+}{plugins: map[bool]*extism.CompiledPlugin{}}
+
+// compile returns QuickJS compiled for the sandbox. Compilations share their
+// cache, so only the first one takes time.
+func (s *Sandbox) compile() (*extism.CompiledPlugin, error) {
+	compiled.mutex.Lock()
+	defer compiled.mutex.Unlock()
+	if plugin, ok := compiled.plugins[s.Network]; ok {
+		return plugin, nil
+	}
+
+	mft := extism.Manifest{
+		Wasm:   []extism.Wasm{extism.WasmData{Data: qjswasm}},
+		Config: map[string]string{},
+	}
+	if s.Network {
+		mft.AllowedHosts = []string{"*"}
+	}
+	config := extism.PluginConfig{
+		EnableWasi:    true,
+		RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(cache).WithCloseOnContextDone(true),
+	}
+	plugin, err := extism.NewCompiledPlugin(ctx, mft, config, []extism.HostFunction{})
+	if err != nil {
+		return nil, err
+	}
+	compiled.plugins[s.Network] = plugin
+	return plugin, nil
+}
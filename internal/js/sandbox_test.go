@@ -0,0 +1,75 @@
+package js
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSandbox(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(os.TempDir(), "project")
+	assert.Equal(t, map[string]string{
+		dir:          filepath.ToSlash(dir),
+		os.TempDir(): filepath.ToSlash(os.TempDir()),
+	}, DefaultSandbox(dir).Mounts)
+
+	cwd, _ := os.Getwd()
+	assert.Contains(t, DefaultSandbox("").Mounts, cwd)
+}
+
+func TestSandboxKey(t *testing.T) {
+	t.Parallel()
+
+	sandbox := &Sandbox{Mounts: map[string]string{"/a": "/a", "/b": "/mnt"}}
+	assert.Equal(t, sandbox.key(), (&Sandbox{Mounts: map[string]string{"/b": "/mnt", "/a": "/a"}}).key())
+
+	// Sandboxes only differing by what is applied to each evaluation share
+	// their instances
+	assert.Equal(t, sandbox.key(), (&Sandbox{Mounts: sandbox.Mounts, Timeout: time.Second, HideEnv: true, Exec: true, Run: true}).key())
+
+	for _, other := range []*Sandbox{
+		{Mounts: map[string]string{"/a": "/a"}},
+		{Mounts: sandbox.Mounts, ReadOnly: true},
+		{Mounts: sandbox.Mounts, MaxMemory: 1 << 20},
+		{Mounts: sandbox.Mounts, Network: true},
+	} {
+		assert.NotEqual(t, sandbox.key(), other.key())
+	}
+}
+
+func TestSandboxRestrict(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(os.TempDir(), "project")
+	sandbox := &Sandbox{
+		Mounts: map[string]string{
+			filepath.Join(root, "dist"): "/dist",
+			filepath.Dir(root):          "/",
+		},
+		MaxMemory: 64 << 20,
+		Network:   true,
+		Exec:      true,
+		Run:       true,
+	}
+	other := &Sandbox{
+		Mounts:   map[string]string{root: "/project"},
+		ReadOnly: true,
+		Timeout:  time.Second,
+		Run:      true,
+	}
+	assert.Equal(t, &Sandbox{
+		Mounts:    map[string]string{filepath.Join(root, "dist"): "/dist"},
+		ReadOnly:  true,
+		MaxMemory: 64 << 20,
+		Timeout:   time.Second,
+		Run:       true,
+	}, sandbox.Restrict(other))
+
+	assert.Equal(t, 32*time.Second, lowestLimit(32*time.Second, 64*time.Second))
+	assert.Equal(t, uint64(0), lowestLimit[uint64](0, 0))
+}
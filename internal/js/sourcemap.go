@@ -0,0 +1,113 @@
+package js
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// base64VLQ are the digits of the Base64 VLQs of source map mappings.
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// sourceMap maps positions in the JavaScript a Civet module was compiled to
+// back to the module, as a version 3 source map does.
+type sourceMap struct {
+	// lines are the mappings of each line of the JavaScript, ordered by
+	// column.
+	lines [][]mapping
+}
+
+// mapping is the position in the source of a column of the JavaScript. All of
+// them start at 0.
+type mapping struct {
+	column       int
+	sourceLine   int
+	sourceColumn int
+}
+
+// parseSourceMap parses a version 3 source map of a single source.
+func parseSourceMap(data []byte) (*sourceMap, error) {
+	var raw struct {
+		Version  int    `json:"version"`
+		Mappings string `json:"mappings"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("js: unsupported source map version %d", raw.Version)
+	}
+	m := &sourceMap{}
+	// The positions in the source are relative to the previous segment,
+	// whatever its line
+	var sourceLine, sourceColumn int
+	for _, line := range strings.Split(raw.Mappings, ";") {
+		var mappings []mapping
+		column := 0
+		for _, segment := range strings.Split(line, ",") {
+			if segment == "" {
+				continue
+			}
+			values, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+			column += values[0]
+			// Segments of one field map nothing
+			if len(values) < 4 {
+				continue
+			}
+			sourceLine += values[2]
+			sourceColumn += values[3]
+			mappings = append(mappings, mapping{column: column, sourceLine: sourceLine, sourceColumn: sourceColumn})
+		}
+		m.lines = append(m.lines, mappings)
+	}
+	return m, nil
+}
+
+// decodeVLQ decodes the fields of a segment of source map mappings.
+func decodeVLQ(segment string) ([]int, error) {
+	var values []int
+	value, shift := 0, 0
+	for i := 0; i < len(segment); i++ {
+		digit := strings.IndexByte(base64VLQ, segment[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("js: invalid source map mappings %q", segment)
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 || len(values) == 0 {
+		return nil, fmt.Errorf("js: invalid source map mappings %q", segment)
+	}
+	return values, nil
+}
+
+// position returns the position in the source of a position of the
+// JavaScript, both starting at 1, from the closest mapping before it on its
+// line.
+func (m *sourceMap) position(line, column int) (int, int, bool) {
+	if line < 1 || line > len(m.lines) {
+		return 0, 0, false
+	}
+	mappings := m.lines[line-1]
+	i := sort.Search(len(mappings), func(i int) bool {
+		return mappings[i].column > column-1
+	})
+	if i == 0 {
+		return 0, 0, false
+	}
+	found := mappings[i-1]
+	return found.sourceLine + 1, found.sourceColumn + 1, true
+}
@@ -0,0 +1,32 @@
+package js
+
+import (
+	"io"
+)
+
+// streamReader forwards the reads of the stdin of an instance to the reader of
+// the script it evaluates, if any. An instance only evaluates a script at a
+// time.
+type streamReader struct {
+	r io.Reader
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	if r.r == nil {
+		return 0, io.EOF
+	}
+	return r.r.Read(p)
+}
+
+// streamWriter forwards the writes of an instance to the writer of the script
+// it evaluates, as they happen, and discards them when there is none.
+type streamWriter struct {
+	w io.Writer
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,179 @@
+package js
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// taskDir is where the files scripts reach Task through are mounted.
+const taskDir = "/dev/task"
+
+//go:embed task.js
+var taskModule []byte
+
+// taskPrelude is prepended to scripts to define the task global. It is kept on
+// the first line, so that the lines of errors match the ones of the script.
+const taskPrelude = `import "` + taskDir + `/task.js"; `
+
+//go:embed realm.js
+var realmModule []byte
+
+// realmSnapshot is evaluated once an instance is created, to define the task
+// global and keep the globals of the realm as they are then. realmRestore
+// restores them once a script was evaluated.
+const (
+	realmSnapshot = `import "` + taskDir + `/task.js"; import "` + taskDir + `/realm.js";`
+	realmRestore  = `import { restore } from "` + taskDir + `/realm.js"; restore();`
+)
+
+// Host is the part of Task scripts reach through the task global.
+type Host interface {
+	// RunTask runs a task with the given variables.
+	RunTask(name string, vars map[string]any) error
+	// Log writes a message to the Task logger, as a warning if warn is set.
+	Log(message string, warn bool)
+	// Exec runs a shell command and returns its stdout.
+	Exec(command string) (string, error)
+}
+
+// taskFS answers the calls of the task global. A call is a read of
+// <method>/<n>/<args>, with args URL encoded JSON, and the content of the file
+// is the JSON response. Responses are kept, so that a call is made once even
+// if the file is opened several times.
+type taskFS struct {
+	mutex     sync.Mutex
+	vars      map[string]any
+	host      Host
+	responses map[string][]byte
+}
+
+type taskResponse struct {
+	Value any    `json:"value,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// reset prepares the file system for the evaluation of a script.
+func (f *taskFS) reset(vars map[string]any, host Host) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	f.vars = vars
+	f.host = host
+	f.responses = map[string][]byte{}
+}
+
+func (f *taskFS) Open(name string) (fs.File, error) {
+	switch name {
+	case ".":
+		return &taskFile{name: name, dir: true}, nil
+	case "task.js":
+		return &taskFile{name: name, Reader: bytes.NewReader(taskModule)}, nil
+	case "realm.js":
+		return &taskFile{name: name, Reader: bytes.NewReader(realmModule)}, nil
+	}
+
+	method, rest, _ := strings.Cut(name, "/")
+	_, request, ok := strings.Cut(rest, "/")
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	response, ok := f.responses[name]
+	if !ok {
+		value, err := f.call(method, request)
+		r := taskResponse{Value: value}
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if response, err = json.Marshal(r); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		f.responses[name] = response
+	}
+	return &taskFile{name: name, Reader: bytes.NewReader(response)}, nil
+}
+
+func (f *taskFS) call(method, request string) (any, error) {
+	request, err := url.PathUnescape(request)
+	if err != nil {
+		return nil, err
+	}
+	var args struct {
+		Name    string
+		Vars    map[string]any
+		Message string
+		Command string
+	}
+	if err := json.Unmarshal([]byte(request), &args); err != nil {
+		return nil, fmt.Errorf("task.%s: invalid arguments: %w", method, err)
+	}
+
+	if method == "vars" {
+		return f.vars, nil
+	}
+	if f.host == nil {
+		return nil, fmt.Errorf("task.%s is only available to commands of tasks", method)
+	}
+	// The tasks and commands may evaluate scripts of their own, which
+	// mustn't wait for the instance of this one
+	switch method {
+	case "run":
+		yield(func() { err = f.host.RunTask(args.Name, args.Vars) })
+		return nil, err
+	case "log", "warn":
+		f.host.Log(args.Message, method == "warn")
+		return nil, nil
+	case "exec":
+		var out string
+		yield(func() { out, err = f.host.Exec(args.Command) })
+		return out, err
+	default:
+		return nil, fmt.Errorf("task.%s is not a function", method)
+	}
+}
+
+// taskFile is a file of taskFS.
+type taskFile struct {
+	*bytes.Reader
+	name string
+	dir  bool
+}
+
+func (f *taskFile) Stat() (fs.FileInfo, error) { return f, nil }
+func (f *taskFile) Close() error               { return nil }
+func (f *taskFile) Name() string               { return f.name }
+func (f *taskFile) ModTime() time.Time         { return time.Time{} }
+func (f *taskFile) IsDir() bool                { return f.dir }
+func (f *taskFile) Sys() any                   { return nil }
+
+func (f *taskFile) Read(b []byte) (int, error) {
+	if f.dir {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+	return f.Reader.Read(b)
+}
+
+func (f *taskFile) Size() int64 {
+	if f.dir {
+		return 0
+	}
+	return f.Reader.Size()
+}
+
+func (f *taskFile) Mode() fs.FileMode {
+	if f.dir {
+		return fs.ModeDir | 0o555
+	}
+	return 0o444
+}
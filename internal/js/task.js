@@ -0,0 +1,41 @@
+// The task global, through which scripts reach Task. Every call is a read of
+// a file of /dev/task, which Task answers before the read returns.
+import * as std from "qjs:std";
+
+let calls = 0;
+
+function call(method, args) {
+  const request = encodeURIComponent(JSON.stringify(args ?? null));
+  const content = std.loadFile(`/dev/task/${method}/${calls++}/${request}`);
+  if (content === null) {
+    throw new Error(`task.${method}: Task did not answer`);
+  }
+  const response = JSON.parse(content);
+  if (response.error) {
+    throw new Error(response.error);
+  }
+  return response.value;
+}
+
+const format = (args) =>
+  args.map((arg) => (typeof arg === "string" ? arg : JSON.stringify(arg))).join(" ");
+
+// The module is only evaluated once by an instance, which is reused for the
+// scripts of several commands, so the variables are read from Task each time.
+globalThis.task = Object.freeze({
+  get vars() {
+    return call("vars");
+  },
+  run(name, vars) {
+    call("run", { name, vars });
+  },
+  log(...args) {
+    call("log", { message: format(args) });
+  },
+  warn(...args) {
+    call("warn", { message: format(args) });
+  },
+  exec(command) {
+    return call("exec", { command });
+  },
+});
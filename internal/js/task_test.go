@@ -0,0 +1,83 @@
+package js
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHost struct {
+	runs []string
+	logs []string
+}
+
+func (h *fakeHost) RunTask(name string, vars map[string]any) error {
+	if name == "missing" {
+		return errors.New(`task: Task "missing" does not exist`)
+	}
+	h.runs = append(h.runs, name)
+	return nil
+}
+
+func (h *fakeHost) Log(message string, warn bool) {
+	if warn {
+		message = "warning: " + message
+	}
+	h.logs = append(h.logs, message)
+}
+
+func (h *fakeHost) Exec(command string) (string, error) {
+	return "ran " + command, nil
+}
+
+func callTaskFS(t *testing.T, f *taskFS, name string, args any) taskResponse {
+	t.Helper()
+	b, err := json.Marshal(args)
+	require.NoError(t, err)
+	content, err := fs.ReadFile(f, name+"/"+url.PathEscape(string(b)))
+	require.NoError(t, err)
+	var response taskResponse
+	require.NoError(t, json.Unmarshal(content, &response))
+	return response
+}
+
+func TestTaskFS(t *testing.T) {
+	t.Parallel()
+
+	host := &fakeHost{}
+	f := &taskFS{}
+	f.reset(map[string]any{"LIST": []any{"a", "b"}, "NAME": "task"}, host)
+
+	info, err := fs.Stat(f, ".")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	module, err := fs.ReadFile(f, "task.js")
+	require.NoError(t, err)
+	assert.Contains(t, string(module), "globalThis.task")
+
+	assert.Equal(t, taskResponse{Value: map[string]any{"LIST": []any{"a", "b"}, "NAME": "task"}}, callTaskFS(t, f, "vars/0", nil))
+	assert.Equal(t, taskResponse{}, callTaskFS(t, f, "run/1", map[string]any{"name": "build"}))
+	assert.Equal(t, taskResponse{Error: `task: Task "missing" does not exist`}, callTaskFS(t, f, "run/2", map[string]any{"name": "missing"}))
+	assert.Equal(t, taskResponse{}, callTaskFS(t, f, "log/3", map[string]any{"message": "hello"}))
+	assert.Equal(t, taskResponse{}, callTaskFS(t, f, "warn/4", map[string]any{"message": "careful"}))
+	assert.Equal(t, taskResponse{Value: "ran echo"}, callTaskFS(t, f, "exec/5", map[string]any{"command": "echo"}))
+	assert.Equal(t, taskResponse{Error: "task.open is not a function"}, callTaskFS(t, f, "open/6", nil))
+
+	// Reading a call again doesn't make it again
+	assert.Equal(t, taskResponse{}, callTaskFS(t, f, "run/1", map[string]any{"name": "build"}))
+	assert.Equal(t, []string{"build"}, host.runs)
+	assert.Equal(t, []string{"hello", "warning: careful"}, host.logs)
+
+	_, err = f.Open("run")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	f.reset(nil, nil)
+	assert.Equal(t, taskResponse{Value: map[string]any{}}, callTaskFS(t, f, "vars/0", nil))
+	assert.Equal(t, taskResponse{Error: "task.run is only available to commands of tasks"}, callTaskFS(t, f, "run/1", map[string]any{"name": "build"}))
+}
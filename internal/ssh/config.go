@@ -0,0 +1,174 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/kevinburke/ssh_config"
+)
+
+// maxJumpDepth guards against jump hosts that recursively jump through
+// themselves because of a catch-all ProxyJump in the configuration.
+const maxJumpDepth = 8
+
+var defaultIdentityFiles = []string{
+	"~/.ssh/id_ed25519",
+	"~/.ssh/id_ecdsa",
+	"~/.ssh/id_rsa",
+}
+
+// resolve returns a copy of the options where everything left empty is
+// completed from the OpenSSH client configuration, the same way `ssh` would
+// do it: Host aliases, HostName, Port, User, IdentityFile and ProxyJump.
+func (options *NewOptions) resolve(depth int) (*NewOptions, error) {
+	if depth > maxJumpDepth {
+		return nil, fmt.Errorf("ssh: too many nested jump hosts for %q", options.Addr)
+	}
+	resolved := *options
+
+	configFile := options.ConfigFile
+	if configFile == "" {
+		configFile = expandHome("~/.ssh/config")
+	}
+	config, err := readConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: unable to read config %q: %w", configFile, err)
+	}
+
+	alias, port, err := net.SplitHostPort(options.Addr)
+	if err != nil {
+		alias, port = options.Addr, ""
+	}
+
+	host := alias
+	if hostname := configGet(config, alias, "HostName"); hostname != "" {
+		host = strings.ReplaceAll(hostname, "%h", alias)
+	}
+	if port == "" {
+		port = configGet(config, alias, "Port")
+	}
+	if port == "" {
+		port = "22"
+	}
+	resolved.Addr = net.JoinHostPort(host, port)
+
+	if resolved.User == "" {
+		resolved.User = configGet(config, alias, "User")
+	}
+	if resolved.User == "" {
+		if current, err := user.Current(); err == nil {
+			resolved.User = current.Username
+		}
+	}
+
+	if options.Key == "" && options.KeyPath == "" {
+		for _, identityFile := range configGetAll(config, alias, "IdentityFile") {
+			resolved.IdentityFiles = append(resolved.IdentityFiles, expandHome(identityFile))
+		}
+		if len(resolved.IdentityFiles) == 0 && options.Password == "" {
+			for _, identityFile := range defaultIdentityFiles {
+				resolved.IdentityFiles = append(resolved.IdentityFiles, expandHome(identityFile))
+			}
+		}
+	}
+
+	jumps := options.Jump
+	if len(jumps) == 0 {
+		if proxyJump := configGet(config, alias, "ProxyJump"); proxyJump != "" && proxyJump != "none" {
+			for _, hop := range strings.Split(proxyJump, ",") {
+				jump, err := parseJump(strings.TrimSpace(hop))
+				if err != nil {
+					return nil, err
+				}
+				jump.KnownHosts = options.KnownHosts
+				jump.Timeout = options.Timeout
+				jump.Insecure = options.Insecure
+				jump.TrustedHostsFile = options.TrustedHostsFile
+				jump.HostKeyPrompt = options.HostKeyPrompt
+				jump.ConfigFile = options.ConfigFile
+				jump.AgentSocket = options.AgentSocket
+				jumps = append(jumps, jump)
+			}
+		}
+	}
+	resolved.Jump = make([]*NewOptions, 0, len(jumps))
+	for _, jump := range jumps {
+		resolvedJump, err := jump.resolve(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		resolved.Jump = append(resolved.Jump, resolvedJump)
+	}
+
+	return &resolved, nil
+}
+
+// parseJump parses a jump host given in the ProxyJump format,
+// [user@]host[:port].
+func parseJump(jump string) (*NewOptions, error) {
+	parsed, err := url.Parse("//" + jump)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: invalid jump host %q: %w", jump, err)
+	}
+	options := &NewOptions{Addr: parsed.Host}
+	if parsed.User != nil {
+		options.User = parsed.User.Username()
+		options.Password, _ = parsed.User.Password()
+	}
+	return options, nil
+}
+
+func readConfig(configFile string) (*ssh_config.Config, error) {
+	f, err := os.Open(configFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ssh_config.Decode(f)
+}
+
+func configGet(config *ssh_config.Config, alias, key string) (value string) {
+	if config == nil {
+		return ""
+	}
+	// The parser panics on Match directives, which are not supported.
+	defer func() {
+		if recover() != nil {
+			value = ""
+		}
+	}()
+	value, _ = config.Get(alias, key)
+	return value
+}
+
+func configGetAll(config *ssh_config.Config, alias, key string) (values []string) {
+	if config == nil {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			values = nil
+		}
+	}()
+	values, _ = config.GetAll(alias, key)
+	return values
+}
+
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
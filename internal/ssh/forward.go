@@ -0,0 +1,159 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type ForwardOptions struct {
+	// Local is the address on the local host, either [host:]port or the path
+	// of a unix socket.
+	Local string
+	// Remote is the address on the remote host, in the same format.
+	Remote string
+	// Reverse forwards the connections made to the remote address to the
+	// local one, instead of the other way round.
+	Reverse bool
+}
+
+// Forward is a running tunnel between the local host and the remote one.
+type Forward struct {
+	listener net.Listener
+	dial     func() (net.Conn, error)
+
+	mutex  sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// Forward starts forwarding the connections made to the local address to the
+// remote address, through the host, or the other way round for reverse
+// forwards. Ports left to 0 are allocated by the listening side, and can be
+// read back from Addr.
+func (s *SshClient) Forward(options ForwardOptions) (*Forward, error) {
+	localNetwork, localAddr := forwardAddr(options.Local, "127.0.0.1")
+	remoteNetwork, remoteAddr := forwardAddr(options.Remote, "localhost")
+
+	f := &Forward{conns: map[net.Conn]struct{}{}}
+	var err error
+	if options.Reverse {
+		if remoteNetwork == "unix" {
+			f.listener, err = s.client.ListenUnix(remoteAddr)
+		} else {
+			f.listener, err = s.client.Listen(remoteNetwork, remoteAddr)
+		}
+		f.dial = func() (net.Conn, error) { return net.Dial(localNetwork, localAddr) }
+	} else {
+		f.listener, err = net.Listen(localNetwork, localAddr)
+		f.dial = func() (net.Conn, error) { return s.client.Dial(remoteNetwork, remoteAddr) }
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ssh: unable to forward %q to %q: %w", options.Local, options.Remote, err)
+	}
+
+	f.wg.Add(1)
+	go f.serve()
+	return f, nil
+}
+
+// forwardAddr returns the network and address of a forward, completing bare
+// ports with the given host.
+func forwardAddr(addr, host string) (string, string) {
+	if strings.Contains(addr, "/") {
+		return "unix", addr
+	}
+	if _, err := strconv.Atoi(addr); err == nil {
+		return "tcp", net.JoinHostPort(host, addr)
+	}
+	if strings.HasPrefix(addr, ":") {
+		return "tcp", host + addr
+	}
+	return "tcp", addr
+}
+
+// Addr returns the address the forward listens on: a local one, or a remote
+// one for reverse forwards.
+func (f *Forward) Addr() net.Addr {
+	return f.listener.Addr()
+}
+
+func (f *Forward) serve() {
+	defer f.wg.Done()
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		if !f.track(conn) {
+			conn.Close()
+			return
+		}
+		f.wg.Add(1)
+		go func() {
+			defer f.wg.Done()
+			defer f.untrack(conn)
+			f.pipe(conn)
+		}()
+	}
+}
+
+func (f *Forward) pipe(conn net.Conn) {
+	target, err := f.dial()
+	if err != nil {
+		return
+	}
+	if !f.track(target) {
+		target.Close()
+		return
+	}
+	defer f.untrack(target)
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(target, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(conn, target)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func (f *Forward) track(conn net.Conn) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.closed {
+		return false
+	}
+	f.conns[conn] = struct{}{}
+	return true
+}
+
+func (f *Forward) untrack(conn net.Conn) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.conns, conn)
+	conn.Close()
+}
+
+// Close stops listening and closes the forwarded connections.
+func (f *Forward) Close() error {
+	f.mutex.Lock()
+	f.closed = true
+	err := f.listener.Close()
+	for conn := range f.conns {
+		conn.Close()
+	}
+	f.mutex.Unlock()
+
+	f.wg.Wait()
+	return err
+}
@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newEchoServer listens on the network and echoes back the lines it reads.
+func newEchoServer(t *testing.T, network, addr string) net.Listener {
+	t.Helper()
+
+	listener, err := net.Listen(network, addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func assertEcho(t *testing.T, network, addr string) {
+	t.Helper()
+
+	conn, err := net.Dial(network, addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = fmt.Fprintln(conn, "ping")
+	require.NoError(t, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "ping\n", line)
+}
+
+func TestForward(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	dir := t.TempDir()
+	tcpEcho := newEchoServer(t, "tcp", "127.0.0.1:0")
+	tcpPort := strconv.Itoa(tcpEcho.Addr().(*net.TCPAddr).Port)
+	unixEcho := newEchoServer(t, "unix", filepath.Join(dir, "echo.sock"))
+
+	tests := []struct {
+		name    string
+		options ForwardOptions
+		network string
+	}{
+		{
+			name:    "local tcp",
+			options: ForwardOptions{Local: "0", Remote: tcpPort},
+			network: "tcp",
+		},
+		{
+			name:    "local unix to remote tcp",
+			options: ForwardOptions{Local: filepath.Join(dir, "local.sock"), Remote: tcpEcho.Addr().String()},
+			network: "unix",
+		},
+		{
+			name:    "local tcp to remote unix",
+			options: ForwardOptions{Local: "127.0.0.1:0", Remote: unixEcho.Addr().String()},
+			network: "tcp",
+		},
+		{
+			name:    "reverse tcp",
+			options: ForwardOptions{Local: tcpPort, Remote: "127.0.0.1:0", Reverse: true},
+			network: "tcp",
+		},
+		{
+			name:    "reverse unix",
+			options: ForwardOptions{Local: unixEcho.Addr().String(), Remote: filepath.Join(dir, "remote.sock"), Reverse: true},
+			network: "unix",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forward, err := client.Forward(test.options)
+			require.NoError(t, err)
+
+			// The test server runs on this host, so remote addresses can be
+			// dialed directly as well.
+			assertEcho(t, test.network, forward.Addr().String())
+			assertEcho(t, test.network, forward.Addr().String())
+
+			require.NoError(t, forward.Close())
+			_, err = net.Dial(test.network, forward.Addr().String())
+			assert.Error(t, err)
+		})
+	}
+}
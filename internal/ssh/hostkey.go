@@ -0,0 +1,101 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// trustMutex serializes the trust prompts and the writes to the trusted hosts
+// files, as several hosts may be dialed at once.
+var trustMutex sync.Mutex
+
+// hostKeyCallback checks the keys of the hosts against the known hosts files
+// and, in trust on first use mode, the trusted hosts file, where the keys of
+// unknown hosts are added once the user trusts them.
+func hostKeyCallback(options *NewOptions) (ssh.HostKeyCallback, error) {
+	if options.Insecure {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	files := options.KnownHosts
+	if options.TrustedHostsFile != "" {
+		if err := touch(options.TrustedHostsFile); err != nil {
+			return nil, fmt.Errorf("ssh: unable to create trusted hosts file: %w", err)
+		}
+		files = append(files[:len(files):len(files)], options.TrustedHostsFile)
+	}
+	known, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, fmt.Errorf("ssh: unable to read known hosts: %w", err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := known(hostname, remote, key)
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		if len(keyErr.Want) > 0 {
+			return &errors.TaskSSHHostKeyChangedError{
+				Host:             hostname,
+				KnownHostsFile:   keyErr.Want[0].Filename,
+				Line:             keyErr.Want[0].Line,
+				KnownFingerprint: ssh.FingerprintSHA256(keyErr.Want[0].Key),
+				Fingerprint:      ssh.FingerprintSHA256(key),
+			}
+		}
+		if options.TrustedHostsFile == "" {
+			return err
+		}
+		return trustHost(options, hostname, remote, key)
+	}, nil
+}
+
+// trustHost asks the user whether to trust the key of an unknown host, and
+// stores it if so.
+func trustHost(options *NewOptions, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	trustMutex.Lock()
+	defer trustMutex.Unlock()
+
+	// The host may have been trusted while waiting for the lock
+	trusted, err := knownhosts.New(options.TrustedHostsFile)
+	if err != nil {
+		return err
+	}
+	if err := trusted(hostname, remote, key); err == nil {
+		return nil
+	}
+
+	fingerprint := ssh.FingerprintSHA256(key)
+	prompt := fmt.Sprintf("The authenticity of host %q can't be established.\n%s key fingerprint is %s.\nDo you want to trust it?", hostname, key.Type(), fingerprint)
+	if options.HostKeyPrompt == nil || options.HostKeyPrompt(prompt) != nil {
+		return &errors.TaskSSHHostNotTrustedError{Host: hostname, Fingerprint: fingerprint}
+	}
+
+	f, err := os.OpenFile(options.TrustedHostsFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key))
+	return err
+}
+
+func touch(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,109 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+func TestHostKeyTrustOnFirstUse(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	trustedHosts := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	prompts := 0
+	options := server.options()
+	options.Insecure = false
+	options.TrustedHostsFile = trustedHosts
+	options.HostKeyPrompt = func(prompt string) error {
+		prompts++
+		assert.Contains(t, prompt, ssh.FingerprintSHA256(server.hostKey.PublicKey()))
+		return nil
+	}
+
+	// The key is stored the first time, and trusted afterwards
+	for range 2 {
+		client, err := NewSshClient(options)
+		require.NoError(t, err)
+		client.Close()
+	}
+	assert.Equal(t, 1, prompts)
+
+	b, err := os.ReadFile(trustedHosts)
+	require.NoError(t, err)
+	assert.Equal(t, knownhosts.Line([]string{knownhosts.Normalize(server.addr)}, server.hostKey.PublicKey())+"\n", string(b))
+}
+
+func TestHostKeyNotTrusted(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	trustedHosts := filepath.Join(t.TempDir(), "known_hosts")
+	options := server.options()
+	options.Insecure = false
+	options.TrustedHostsFile = trustedHosts
+	options.HostKeyPrompt = func(prompt string) error { return errors.New("cancelled") }
+
+	_, err := NewSshClient(options)
+	var notTrustedErr *errors.TaskSSHHostNotTrustedError
+	require.True(t, errors.As(err, &notTrustedErr), "unexpected error: %v", err)
+	assert.Equal(t, ssh.FingerprintSHA256(server.hostKey.PublicKey()), notTrustedErr.Fingerprint)
+
+	b, err := os.ReadFile(trustedHosts)
+	require.NoError(t, err)
+	assert.Empty(t, b)
+}
+
+func TestHostKeyChanged(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldKey, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+
+	for _, tofu := range []bool{false, true} {
+		knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(knownHosts, []byte(knownhosts.Line([]string{server.addr}, oldKey)+"\n"), 0o600))
+		options := server.options()
+		options.Insecure = false
+		options.KnownHosts = []string{knownHosts}
+		if tofu {
+			options.TrustedHostsFile = filepath.Join(t.TempDir(), "trusted_hosts")
+			options.HostKeyPrompt = func(prompt string) error {
+				t.Error("unexpected prompt")
+				return nil
+			}
+		}
+
+		_, err = NewSshClient(options)
+		var keyChangedErr *errors.TaskSSHHostKeyChangedError
+		require.True(t, errors.As(err, &keyChangedErr), "unexpected error: %v", err)
+		assert.Equal(t, knownHosts, keyChangedErr.KnownHostsFile)
+		assert.Equal(t, 1, keyChangedErr.Line)
+		assert.Equal(t, ssh.FingerprintSHA256(oldKey), keyChangedErr.KnownFingerprint)
+		assert.Equal(t, ssh.FingerprintSHA256(server.hostKey.PublicKey()), keyChangedErr.Fingerprint)
+	}
+}
+
+func TestHostKeyMissingKnownHosts(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	options := server.options()
+	options.Insecure = false
+	options.KnownHosts = []string{filepath.Join(t.TempDir(), "missing")}
+
+	_, err := NewSshClient(options)
+	require.ErrorContains(t, err, "unable to read known hosts")
+}
@@ -0,0 +1,104 @@
+package ssh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrPoolClosed is returned when a client is requested from a [Pool] while
+// it is being closed.
+var ErrPoolClosed = errors.New("ssh: connection pool closed")
+
+// Pool keeps a single connection per distinct set of [NewOptions] so that
+// every task targeting the same host shares one client. Commands run in their
+// own sessions, which are multiplexed over the pooled connection.
+type Pool struct {
+	clients map[string]*poolEntry
+	mutex   sync.Mutex
+}
+
+type poolEntry struct {
+	once   sync.Once
+	client *SshClient
+	err    error
+}
+
+func NewPool() *Pool {
+	return &Pool{clients: map[string]*poolEntry{}}
+}
+
+// Get returns the pooled client for the given options, dialing a new
+// connection if none exists yet. Concurrent callers asking for the same
+// options wait for a single dial. Connections that are closed by the remote
+// end are evicted so the next call dials again.
+func (p *Pool) Get(options *NewOptions) (*SshClient, error) {
+	key, err := poolKey(options)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	entry, ok := p.clients[key]
+	if !ok {
+		entry = &poolEntry{}
+		p.clients[key] = entry
+	}
+	p.mutex.Unlock()
+
+	entry.once.Do(func() {
+		entry.client, entry.err = NewSshClient(options)
+		if entry.err != nil {
+			p.remove(key, entry)
+			return
+		}
+		go func() {
+			_ = entry.client.client.Wait()
+			p.remove(key, entry)
+		}()
+	})
+	return entry.client, entry.err
+}
+
+func (p *Pool) remove(key string, entry *poolEntry) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.clients[key] == entry {
+		delete(p.clients, key)
+	}
+}
+
+// Close closes every pooled connection. The pool stays usable afterwards and
+// will dial new connections on demand.
+func (p *Pool) Close() error {
+	p.mutex.Lock()
+	entries := p.clients
+	p.clients = map[string]*poolEntry{}
+	p.mutex.Unlock()
+
+	var errs []error
+	for _, entry := range entries {
+		// Waits for an in-flight dial, or marks the entry as unusable.
+		entry.once.Do(func() {
+			entry.err = ErrPoolClosed
+		})
+		if entry.client != nil {
+			if err := entry.client.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func poolKey(options *NewOptions) (string, error) {
+	b, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,68 @@
+package ssh
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolReusesConnections(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	pool := NewPool()
+	defer pool.Close()
+
+	var wg sync.WaitGroup
+	clients := make([]*SshClient, 5)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client, err := pool.Get(server.options())
+			assert.NoError(t, err)
+			clients[i] = client
+		}()
+	}
+	wg.Wait()
+
+	for _, client := range clients {
+		require.Same(t, clients[0], client)
+	}
+	assert.Equal(t, int32(1), server.conns.Load())
+
+	var wg2 sync.WaitGroup
+	for range 5 {
+		wg2.Add(1)
+		go func() {
+			defer wg2.Done()
+			var stdout bytes.Buffer
+			err := clients[0].Run(t.Context(), &RunOptions{Commands: []string{"echo foo"}, Stdout: &stdout})
+			assert.NoError(t, err)
+			assert.Equal(t, "foo\n", stdout.String())
+		}()
+	}
+	wg2.Wait()
+	assert.Equal(t, int32(1), server.conns.Load())
+}
+
+func TestPoolClose(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	pool := NewPool()
+
+	client, err := pool.Get(server.options())
+	require.NoError(t, err)
+	require.NoError(t, pool.Close())
+	require.Error(t, client.Run(t.Context(), &RunOptions{Commands: []string{"true"}}))
+
+	client, err = pool.Get(server.options())
+	require.NoError(t, err)
+	require.NoError(t, client.Run(t.Context(), &RunOptions{Commands: []string{"true"}}))
+	require.NoError(t, pool.Close())
+	assert.Equal(t, int32(2), server.conns.Load())
+}
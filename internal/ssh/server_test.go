@@ -0,0 +1,430 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	testUser     = "root"
+	testPassword = "foobar"
+)
+
+// testServer is a minimal in-process SSH server which runs the requested
+// commands with the local sh.
+type testServer struct {
+	addr           string
+	hostKey        ssh.Signer
+	authorizedKeys []ssh.PublicKey
+	conns          atomic.Int32
+	forwards       atomic.Int32
+
+	ttyMutex sync.Mutex
+	// ttyRequests records the pty-req and window-change requests received.
+	ttyRequests []string
+}
+
+func (s *testServer) recordTty(request string) {
+	s.ttyMutex.Lock()
+	defer s.ttyMutex.Unlock()
+	s.ttyRequests = append(s.ttyRequests, request)
+}
+
+func (s *testServer) ttys() []string {
+	s.ttyMutex.Lock()
+	defer s.ttyMutex.Unlock()
+	return slices.Clone(s.ttyRequests)
+}
+
+func newTestServer(t *testing.T, authorizedKeys ...ssh.PublicKey) *testServer {
+	t.Helper()
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	hostKey, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == testUser && string(password) == testPassword {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			for _, authorized := range authorizedKeys {
+				if conn.User() == testUser && bytes.Equal(authorized.Marshal(), key.Marshal()) {
+					return nil, nil
+				}
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testServer{addr: listener.Addr().String(), hostKey: hostKey, authorizedKeys: authorizedKeys}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handleConn(conn, config)
+		}
+	}()
+	return s
+}
+
+func (s *testServer) options() *NewOptions {
+	return &NewOptions{
+		Addr:       s.addr,
+		User:       testUser,
+		Password:   testPassword,
+		Insecure:   true,
+		ConfigFile: os.DevNull,
+	}
+}
+
+func (s *testServer) handleConn(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.conns.Add(1)
+	go s.handleGlobalRequests(serverConn, reqs)
+	for newChannel := range chans {
+		switch newChannel.ChannelType() {
+		case "session":
+			channel, requests, err := newChannel.Accept()
+			if err != nil {
+				continue
+			}
+			go s.handleSession(channel, requests)
+		case "direct-tcpip":
+			var payload struct {
+				Host       string
+				Port       uint32
+				OriginHost string
+				OriginPort uint32
+			}
+			if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			go s.handleDirect(newChannel, "tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+		case "direct-streamlocal@openssh.com":
+			var payload struct {
+				SocketPath string
+				Reserved0  string
+				Reserved1  uint32
+			}
+			if err := ssh.Unmarshal(newChannel.ExtraData(), &payload); err != nil {
+				_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+				continue
+			}
+			go s.handleDirect(newChannel, "unix", payload.SocketPath)
+		default:
+			_ = newChannel.Reject(ssh.UnknownChannelType, "unsupported channel type")
+		}
+	}
+}
+
+func (s *testServer) handleDirect(newChannel ssh.NewChannel, network, addr string) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		_ = newChannel.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	s.forwards.Add(1)
+	go ssh.DiscardRequests(requests)
+	pipeTestConn(channel, conn)
+}
+
+// handleGlobalRequests serves the reverse forwards, for TCP and unix sockets.
+func (s *testServer) handleGlobalRequests(conn *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	listeners := map[string]net.Listener{}
+	for req := range reqs {
+		var listener net.Listener
+		var err error
+		var channelData func(net.Conn) []byte
+		switch req.Type {
+		case "cancel-tcpip-forward":
+			var payload struct {
+				Addr string
+				Port uint32
+			}
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			key := net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port)))
+			if listener, ok := listeners[key]; ok {
+				listener.Close()
+				delete(listeners, key)
+			}
+			_ = req.Reply(true, nil)
+			continue
+		case "cancel-streamlocal-forward@openssh.com":
+			var payload struct{ SocketPath string }
+			_ = ssh.Unmarshal(req.Payload, &payload)
+			if listener, ok := listeners[payload.SocketPath]; ok {
+				listener.Close()
+				delete(listeners, payload.SocketPath)
+			}
+			_ = req.Reply(true, nil)
+			continue
+		case "tcpip-forward":
+			var payload struct {
+				Addr string
+				Port uint32
+			}
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			listener, err = net.Listen("tcp", net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port))))
+			if err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			port := uint32(listener.Addr().(*net.TCPAddr).Port)
+			listeners[net.JoinHostPort(payload.Addr, strconv.Itoa(int(port)))] = listener
+			_ = req.Reply(true, ssh.Marshal(struct{ Port uint32 }{port}))
+			channelData = func(c net.Conn) []byte {
+				origin := c.RemoteAddr().(*net.TCPAddr)
+				return ssh.Marshal(struct {
+					Addr       string
+					Port       uint32
+					OriginAddr string
+					OriginPort uint32
+				}{payload.Addr, port, origin.IP.String(), uint32(origin.Port)})
+			}
+		case "streamlocal-forward@openssh.com":
+			var payload struct{ SocketPath string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			listener, err = net.Listen("unix", payload.SocketPath)
+			if err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			listeners[payload.SocketPath] = listener
+			_ = req.Reply(true, nil)
+			channelData = func(net.Conn) []byte {
+				return ssh.Marshal(struct{ SocketPath, Reserved string }{SocketPath: payload.SocketPath})
+			}
+		default:
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+			continue
+		}
+
+		s.forwards.Add(1)
+		channelType := "forwarded-tcpip"
+		if req.Type != "tcpip-forward" {
+			channelType = "forwarded-streamlocal@openssh.com"
+		}
+		go func() {
+			defer listener.Close()
+			go func() {
+				_ = conn.Wait()
+				listener.Close()
+			}()
+			for {
+				c, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				channel, requests, err := conn.OpenChannel(channelType, channelData(c))
+				if err != nil {
+					c.Close()
+					continue
+				}
+				go ssh.DiscardRequests(requests)
+				go pipeTestConn(channel, c)
+			}
+		}()
+	}
+}
+
+func pipeTestConn(channel ssh.Channel, conn net.Conn) {
+	go func() {
+		defer channel.Close()
+		_, _ = io.Copy(channel, conn)
+	}()
+	go func() {
+		defer conn.Close()
+		_, _ = io.Copy(conn, channel)
+	}()
+}
+
+// startTestCommand starts the command of a session, which runs in its own
+// process group so that signals reach its children too, and reports its exit
+// status once it exits.
+func startTestCommand(channel ssh.Channel, env, args []string) *exec.Cmd {
+	cmd := exec.Command("sh", args...)
+	cmd.Env = env
+	cmd.Stdout = channel
+	cmd.Stderr = channel.Stderr()
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Like sshd, the input isn't waited for once the command exits, as it
+	// never ends for interactive sessions
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		go func() {
+			_, _ = io.Copy(stdin, channel)
+			stdin.Close()
+		}()
+		err = cmd.Start()
+	}
+	if err != nil {
+		sendExitStatus(channel, err)
+		channel.Close()
+		return nil
+	}
+	go func() {
+		defer channel.Close()
+		sendExitStatus(channel, cmd.Wait())
+	}()
+	return cmd
+}
+
+func sendExitStatus(channel ssh.Channel, err error) {
+	code := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			code = 127
+		} else if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			name := ""
+			for signal, number := range signalNumbers {
+				if number == int(status.Signal()) {
+					name = signal
+				}
+			}
+			_, _ = channel.SendRequest("exit-signal", false, ssh.Marshal(struct {
+				Signal     string
+				CoreDumped bool
+				Error      string
+				Lang       string
+			}{Signal: name}))
+			return
+		} else {
+			code = exitErr.ExitCode()
+		}
+	}
+	status := make([]byte, 4)
+	binary.BigEndian.PutUint32(status, uint32(code))
+	_, _ = channel.SendRequest("exit-status", false, status)
+}
+
+func (s *testServer) handleSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	defer channel.Close()
+
+	env := os.Environ()
+	var cmd *exec.Cmd
+	for req := range requests {
+		switch req.Type {
+		case "env":
+			var payload struct{ Name, Value string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			env = append(env, payload.Name+"="+payload.Value)
+			_ = req.Reply(true, nil)
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			server, err := sftp.NewServer(channel)
+			if err != nil {
+				return
+			}
+			_ = server.Serve()
+			return
+		case "pty-req":
+			var payload struct {
+				Term          string
+				Width, Height uint32
+				PixelWidth    uint32
+				PixelHeight   uint32
+				Modes         string
+			}
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			env = append(env, "TERM="+payload.Term)
+			s.recordTty(fmt.Sprintf("pty-req %s %dx%d", payload.Term, payload.Width, payload.Height))
+			_ = req.Reply(true, nil)
+		case "window-change":
+			var payload struct {
+				Width, Height uint32
+				PixelWidth    uint32
+				PixelHeight   uint32
+			}
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				continue
+			}
+			s.recordTty(fmt.Sprintf("window-change %dx%d", payload.Width, payload.Height))
+		case "shell", "exec":
+			args := []string{}
+			if req.Type == "exec" {
+				var payload struct{ Command string }
+				if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+					_ = req.Reply(false, nil)
+					continue
+				}
+				args = append(args, "-c", payload.Command)
+			}
+			_ = req.Reply(true, nil)
+
+			// The command runs aside, so that requests like window-change are
+			// still handled meanwhile
+			cmd = startTestCommand(channel, env, args)
+		case "signal":
+			var payload struct{ Signal string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || cmd == nil {
+				continue
+			}
+			if number, ok := signalNumbers[payload.Signal]; ok {
+				_ = syscall.Kill(-cmd.Process.Pid, syscall.Signal(number))
+			}
+		default:
+			_ = req.Reply(false, nil)
+		}
+	}
+	_, _ = io.Copy(io.Discard, channel)
+}
@@ -0,0 +1,425 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/sftp"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+// sftpClient returns the SFTP session of the client, started on first use.
+func (s *SshClient) sftpClient() (*sftp.Client, error) {
+	s.sftpMutex.Lock()
+	defer s.sftpMutex.Unlock()
+
+	if s.sftp == nil {
+		client, err := sftp.NewClient(s.client)
+		if err != nil {
+			return nil, fmt.Errorf("ssh: unable to start sftp: %w", err)
+		}
+		s.sftp = client
+	}
+	return s.sftp, nil
+}
+
+type UploadOptions = []struct {
+	Source string
+	Target string
+}
+
+// DownloadOptions lists the remote files to fetch. Sources may be files,
+// directories or glob patterns on the remote host.
+type DownloadOptions = UploadOptions
+
+// Upload copies local files, directories and symlinks to the host over SFTP.
+// Directories are copied recursively into the target, while a file is copied
+// into the target if it ends with a slash or is an existing directory. Modes
+// and modification times are preserved, and files with the same size,
+// modification time and content as their target are skipped.
+func (s *SshClient) Upload(options UploadOptions) error {
+	client, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	for _, upload := range options {
+		info, err := os.Lstat(upload.Source)
+		if err != nil {
+			return err
+		}
+		target := upload.Target
+		if !info.IsDir() {
+			if stat, err := client.Stat(target); strings.HasSuffix(target, "/") || (err == nil && stat.IsDir()) {
+				target = path.Join(target, filepath.Base(upload.Source))
+			}
+			if err := uploadEntry(client, upload.Source, info, target); err != nil {
+				return err
+			}
+			continue
+		}
+
+		err = filepath.WalkDir(upload.Source, func(source string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(upload.Source, source)
+			if err != nil {
+				return err
+			}
+			return uploadEntry(client, source, info, path.Join(upload.Target, filepath.ToSlash(rel)))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func uploadEntry(client *sftp.Client, source string, info fs.FileInfo, target string) error {
+	switch {
+	case info.IsDir():
+		if err := client.MkdirAll(target); err != nil {
+			return fmt.Errorf("ssh: unable to create directory %q: %w", target, err)
+		}
+		return client.Chmod(target, info.Mode().Perm())
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := os.Readlink(source)
+		if err != nil {
+			return err
+		}
+		if current, err := client.ReadLink(target); err == nil && current == link {
+			return nil
+		}
+		if err := client.MkdirAll(path.Dir(target)); err != nil {
+			return err
+		}
+		_ = client.Remove(target)
+		return client.Symlink(link, target)
+	case info.Mode().IsRegular():
+		return uploadFile(client, source, info, target)
+	default:
+		return fmt.Errorf("ssh: unable to upload %q: not a regular file", source)
+	}
+}
+
+func uploadFile(client *sftp.Client, source string, info fs.FileInfo, target string) error {
+	if remote, err := client.Stat(target); err == nil && sameFile(info, remote) {
+		same, err := sameChecksum(source, func() (io.ReadCloser, error) { return client.Open(target) })
+		if err != nil {
+			return err
+		}
+		if same {
+			return nil
+		}
+	}
+
+	f, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := client.MkdirAll(path.Dir(target)); err != nil {
+		return fmt.Errorf("ssh: unable to create directory %q: %w", path.Dir(target), err)
+	}
+	remote, err := client.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("ssh: unable to upload %q: %w", target, err)
+	}
+	if _, err := remote.ReadFrom(f); err != nil {
+		remote.Close()
+		return fmt.Errorf("ssh: unable to upload %q: %w", target, err)
+	}
+	if err := remote.Close(); err != nil {
+		return err
+	}
+	if err := client.Chmod(target, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return client.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// Download copies remote files, directories and symlinks to the local host
+// over SFTP, with the same rules as Upload. Glob sources are copied into the
+// target, relative to the directory the pattern starts in.
+func (s *SshClient) Download(options DownloadOptions) error {
+	client, err := s.sftpClient()
+	if err != nil {
+		return err
+	}
+
+	for _, download := range options {
+		if !IsGlob(download.Source) {
+			if err := downloadPath(client, download.Source, download.Target, false); err != nil {
+				return err
+			}
+			continue
+		}
+
+		matches, err := client.Glob(download.Source)
+		if err != nil {
+			return fmt.Errorf("ssh: invalid pattern %q: %w", download.Source, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("ssh: no files match the download %q", download.Source)
+		}
+		base := GlobBase(download.Source)
+		for _, match := range matches {
+			rel := strings.TrimPrefix(strings.TrimPrefix(match, base), "/")
+			if err := downloadPath(client, match, filepath.Join(download.Target, filepath.FromSlash(rel)), true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// downloadPath downloads a single remote path. exact is set when the target is
+// the final path of the source, as for glob matches.
+func downloadPath(client *sftp.Client, source, target string, exact bool) error {
+	info, err := client.Lstat(source)
+	if err != nil {
+		return fmt.Errorf("ssh: unable to download %q: %w", source, err)
+	}
+	if !info.IsDir() {
+		if !exact {
+			if stat, err := os.Stat(target); strings.HasSuffix(target, "/") || strings.HasSuffix(target, string(filepath.Separator)) || (err == nil && stat.IsDir()) {
+				target = filepath.Join(target, path.Base(source))
+			}
+		}
+		return downloadEntry(client, source, info, target)
+	}
+
+	walker := client.Walk(source)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), source), "/")
+		if err := downloadEntry(client, walker.Path(), walker.Stat(), filepath.Join(target, filepath.FromSlash(rel))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downloadEntry(client *sftp.Client, source string, info fs.FileInfo, target string) error {
+	switch {
+	case info.IsDir():
+		return os.MkdirAll(target, info.Mode().Perm()|0o700)
+	case info.Mode()&fs.ModeSymlink != 0:
+		link, err := client.ReadLink(source)
+		if err != nil {
+			return err
+		}
+		if current, err := os.Readlink(target); err == nil && current == link {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		_ = os.Remove(target)
+		return os.Symlink(link, target)
+	case info.Mode().IsRegular():
+		return downloadFile(client, source, info, target)
+	default:
+		return fmt.Errorf("ssh: unable to download %q: not a regular file", source)
+	}
+}
+
+func downloadFile(client *sftp.Client, source string, info fs.FileInfo, target string) error {
+	if local, err := os.Stat(target); err == nil && sameFile(local, info) {
+		same, err := sameChecksum(target, func() (io.ReadCloser, error) { return client.Open(source) })
+		if err != nil {
+			return err
+		}
+		if same {
+			return nil
+		}
+	}
+
+	remote, err := client.Open(source)
+	if err != nil {
+		return fmt.Errorf("ssh: unable to download %q: %w", source, err)
+	}
+	defer remote.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := remote.WriteTo(f); err != nil {
+		f.Close()
+		return fmt.Errorf("ssh: unable to download %q: %w", source, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(target, info.ModTime(), info.ModTime())
+}
+
+// sameFile reports whether both regular files have the same size and
+// modification time. Times are compared to the second, as it is all SFTP keeps.
+// Their content is only compared by sameChecksum when they do.
+func sameFile(a, b fs.FileInfo) bool {
+	return a.Mode().IsRegular() && b.Mode().IsRegular() &&
+		a.Size() == b.Size() && a.ModTime().Unix() == b.ModTime().Unix()
+}
+
+// sameChecksum reports whether the local file has the same content as the
+// other one.
+func sameChecksum(local string, open func() (io.ReadCloser, error)) (bool, error) {
+	localSum, err := checksum(func() (io.ReadCloser, error) { return os.Open(local) })
+	if err != nil {
+		return false, err
+	}
+	otherSum, err := checksum(open)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(localSum, otherSum), nil
+}
+
+func checksum(open func() (io.ReadCloser, error)) ([]byte, error) {
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// RemoteFile is a regular file found on the host.
+type RemoteFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Glob returns the regular files of the host matching the pattern, sorted by
+// path. Besides the path.Match syntax, ** matches any number of directories.
+// Relative patterns are resolved from the login directory.
+func (s *SshClient) Glob(pattern string) ([]RemoteFile, error) {
+	client, err := s.sftpClient()
+	if err != nil {
+		return nil, err
+	}
+	pattern = path.Clean(pattern)
+
+	var files []RemoteFile
+	addFile := func(name string, info fs.FileInfo) {
+		if info.Mode().IsRegular() {
+			files = append(files, RemoteFile{Path: name, Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+
+	switch {
+	case !IsGlob(pattern):
+		info, err := client.Stat(pattern)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		addFile(pattern, info)
+	case !strings.Contains(pattern, "**"):
+		matches, err := client.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("ssh: invalid pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := client.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			addFile(match, info)
+		}
+	default:
+		base := GlobBase(pattern)
+		if base == "" {
+			base = "."
+		}
+		walker := client.Walk(base)
+		for walker.Step() {
+			if err := walker.Err(); err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				return nil, err
+			}
+			if matchGlob(strings.Split(pattern, "/"), strings.Split(walker.Path(), "/")) {
+				addFile(walker.Path(), walker.Stat())
+			}
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// matchGlob matches the segments of a path against the ones of a pattern,
+// where ** matches any number of segments.
+func matchGlob(pattern, name []string) bool {
+	if len(pattern) == 0 {
+		return len(name) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			if matchGlob(pattern[1:], name[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(name) == 0 {
+		return false
+	}
+	if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+		return false
+	}
+	return matchGlob(pattern[1:], name[1:])
+}
+
+// IsGlob reports whether the path contains any glob pattern character.
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// GlobBase returns the directory a slash separated glob pattern starts in,
+// that is its longest leading path without any pattern character.
+func GlobBase(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if IsGlob(part) {
+			return strings.Join(parts[:i], "/")
+		}
+	}
+	return path.Dir(pattern)
+}
@@ -0,0 +1,194 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func assertTestFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(b))
+}
+
+func TestUpload(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	source := t.TempDir()
+	writeTestFiles(t, source, map[string]string{
+		"a.txt":     "a",
+		"sub/b.txt": "b",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(source, "a.txt"), 0o600))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(source, "link")))
+	target := t.TempDir()
+
+	require.NoError(t, client.Upload(UploadOptions{
+		{Source: source, Target: filepath.Join(target, "dir")},
+		{Source: filepath.Join(source, "a.txt"), Target: target},
+		{Source: filepath.Join(source, "sub", "b.txt"), Target: filepath.Join(target, "new") + "/"},
+		{Source: filepath.Join(source, "sub", "b.txt"), Target: filepath.Join(target, "renamed.txt")},
+	}))
+
+	assertTestFile(t, filepath.Join(target, "dir", "a.txt"), "a")
+	assertTestFile(t, filepath.Join(target, "dir", "sub", "b.txt"), "b")
+	assertTestFile(t, filepath.Join(target, "a.txt"), "a")
+	assertTestFile(t, filepath.Join(target, "new", "b.txt"), "b")
+	assertTestFile(t, filepath.Join(target, "renamed.txt"), "b")
+
+	info, err := os.Stat(filepath.Join(target, "dir", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	link, err := os.Readlink(filepath.Join(target, "dir", "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "a.txt", link)
+
+	// Unchanged files are skipped, which leaves their modification time alone
+	uploaded := filepath.Join(target, "dir", "sub", "b.txt")
+	old := time.Unix(0, 0)
+	require.NoError(t, os.Chtimes(filepath.Join(source, "sub", "b.txt"), old, old))
+	require.NoError(t, os.Chtimes(uploaded, old, old))
+	require.NoError(t, os.Chmod(uploaded, 0o444))
+	require.NoError(t, client.Upload(UploadOptions{{Source: source, Target: filepath.Join(target, "dir")}}))
+	info, err = os.Stat(uploaded)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o444), info.Mode().Perm())
+
+	// Files with the same size and modification time but another content are
+	// uploaded again
+	require.NoError(t, os.Chmod(uploaded, 0o644))
+	writeTestFiles(t, target, map[string]string{"dir/sub/b.txt": "x"})
+	require.NoError(t, os.Chtimes(uploaded, old, old))
+	require.NoError(t, client.Upload(UploadOptions{{Source: source, Target: filepath.Join(target, "dir")}}))
+	assertTestFile(t, uploaded, "b")
+	info, err = os.Stat(filepath.Join(source, "sub", "b.txt"))
+	require.NoError(t, err)
+
+	writeTestFiles(t, source, map[string]string{"sub/b.txt": "c"})
+	changed := info.ModTime().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(source, "sub", "b.txt"), changed, changed))
+	require.NoError(t, client.Upload(UploadOptions{{Source: source, Target: filepath.Join(target, "dir")}}))
+	assertTestFile(t, uploaded, "c")
+}
+
+func TestDownload(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	source := t.TempDir()
+	writeTestFiles(t, source, map[string]string{
+		"logs/a.log":     "a",
+		"logs/b.log":     "b",
+		"logs/c.txt":     "c",
+		"out/bin/app":    "app",
+		"out/readme.txt": "readme",
+	})
+	target := t.TempDir()
+
+	require.NoError(t, client.Download(DownloadOptions{
+		{Source: filepath.ToSlash(filepath.Join(source, "logs", "*.log")), Target: filepath.Join(target, "logs")},
+		{Source: filepath.ToSlash(filepath.Join(source, "out")), Target: filepath.Join(target, "out")},
+		{Source: filepath.ToSlash(filepath.Join(source, "out", "readme.txt")), Target: target},
+	}))
+
+	assertTestFile(t, filepath.Join(target, "logs", "a.log"), "a")
+	assertTestFile(t, filepath.Join(target, "logs", "b.log"), "b")
+	assert.NoFileExists(t, filepath.Join(target, "logs", "c.txt"))
+	assertTestFile(t, filepath.Join(target, "out", "bin", "app"), "app")
+	assertTestFile(t, filepath.Join(target, "out", "readme.txt"), "readme")
+	assertTestFile(t, filepath.Join(target, "readme.txt"), "readme")
+
+	err = client.Download(DownloadOptions{
+		{Source: filepath.ToSlash(filepath.Join(source, "logs", "*.csv")), Target: filepath.Join(target, "logs")},
+	})
+	require.ErrorContains(t, err, "no files match the download")
+}
+
+func TestGlobBase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		base    string
+	}{
+		{pattern: "*.log", base: ""},
+		{pattern: "logs/*.log", base: "logs"},
+		{pattern: "/var/log/**/*.log", base: "/var/log"},
+		{pattern: "/var/log/app.log", base: "/var/log"},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.base, GlobBase(test.pattern), test.pattern)
+	}
+}
+
+func TestGlob(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"a.go":          "a",
+		"b.txt":         "b",
+		"sub/c.go":      "c",
+		"sub/deep/d.go": "d",
+	})
+	root := filepath.ToSlash(dir)
+
+	tests := []struct {
+		pattern string
+		files   []string
+	}{
+		{pattern: root + "/a.go", files: []string{"a.go"}},
+		{pattern: root + "/missing.go"},
+		{pattern: root + "/sub"},
+		{pattern: root + "/*.go", files: []string{"a.go"}},
+		{pattern: root + "/**/*.go", files: []string{"a.go", "sub/c.go", "sub/deep/d.go"}},
+		{pattern: root + "/sub/**/*", files: []string{"sub/c.go", "sub/deep/d.go"}},
+	}
+	for _, test := range tests {
+		files, err := client.Glob(test.pattern)
+		require.NoError(t, err, test.pattern)
+
+		var paths []string
+		for _, file := range files {
+			paths = append(paths, strings.TrimPrefix(file.Path, root+"/"))
+		}
+		assert.Equal(t, test.files, paths, test.pattern)
+	}
+
+	files, err := client.Glob(root + "/b.txt")
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, int64(1), files[0].Size)
+}
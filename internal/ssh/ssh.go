@@ -1,23 +1,41 @@
 package ssh
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
-	"path"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/knownhosts"
-	"golang.org/x/sync/errgroup"
+	"golang.org/x/crypto/ssh/agent"
+
+	"github.com/go-task/task/v3/errors"
 )
 
 type SshClient struct {
-	client   *ssh.Client
-	uploaded *sync.Once
+	client *ssh.Client
+	// addr is the resolved address of the host, used in errors.
+	addr string
+
+	sftpMutex sync.Mutex
+	sftp      *sftp.Client
+	// jumps are the clients of the jump hosts used to reach the target,
+	// closed together with it.
+	jumps []*ssh.Client
+	// signalTimeout is how long a cancelled command is given to exit before
+	// the next signal is sent.
+	signalTimeout time.Duration
 }
 
+// cancelSignals are sent in turn to the commands of a cancelled context, like
+// the signals Task forwards to local commands when interrupted repeatedly.
+var cancelSignals = []ssh.Signal{ssh.SIGINT, ssh.SIGTERM, ssh.SIGKILL}
+
 type NewOptions struct {
 	Addr       string
 	User       string
@@ -27,59 +45,154 @@ type NewOptions struct {
 	KnownHosts []string
 	Timeout    int
 	Insecure   bool
+	// Jump is the chain of hosts to tunnel through before reaching Addr.
+	Jump []*NewOptions
+	// IdentityFiles are private keys tried in addition to Key and KeyPath,
+	// usually resolved from the OpenSSH configuration.
+	IdentityFiles []string
+	// ConfigFile is the OpenSSH client configuration used to resolve the
+	// options. Defaults to ~/.ssh/config.
+	ConfigFile string
+	// AgentSocket is the unix socket of the SSH agent. Defaults to
+	// SSH_AUTH_SOCK.
+	AgentSocket string
+	// TrustedHostsFile enables trust on first use: the keys of the hosts
+	// missing from KnownHosts are added to it once HostKeyPrompt accepts them.
+	TrustedHostsFile string
+	// HostKeyPrompt asks the user whether to trust the key of a host, and
+	// returns an error if not.
+	HostKeyPrompt func(prompt string) error `json:"-"`
 }
 
 func NewSshClient(options *NewOptions) (*SshClient, error) {
+	resolved, err := options.resolve(0)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SshClient{addr: resolved.Addr, signalTimeout: 2 * time.Second}
+	var jump *ssh.Client
+	for _, hop := range resolved.Jump {
+		jump, err = dial(jump, hop)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("ssh: unable to connect jump host %q: %w", hop.Addr, err)
+		}
+		s.jumps = append(s.jumps, jump)
+	}
+	s.client, err = dial(jump, resolved)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	// Make sure the server lets us open sessions before handing out the client.
+	session, err := s.client.NewSession()
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+	session.Close()
+	return s, nil
+}
+
+// dial connects to the host described by the resolved options, tunneling
+// through the given jump client if it is not nil.
+func dial(jump *ssh.Client, options *NewOptions) (*ssh.Client, error) {
+	signers, closeAgent, err := signers(options)
+	if err != nil {
+		return nil, err
+	}
+	defer closeAgent()
+
 	auth := []ssh.AuthMethod{}
+	if len(signers) > 0 {
+		auth = append(auth, ssh.PublicKeys(signers...))
+	}
+	if len(options.Password) > 0 {
+		auth = append(auth, ssh.Password(options.Password))
+	}
+
+	hostKeyCallback, err := hostKeyCallback(options)
+	if err != nil {
+		return nil, err
+	}
+	config := &ssh.ClientConfig{
+		User:            options.User,
+		Auth:            auth,
+		Timeout:         time.Duration(options.Timeout) * time.Second,
+		HostKeyCallback: hostKeyCallback,
+	}
+
+	if jump == nil {
+		return ssh.Dial("tcp", options.Addr, config)
+	}
+	conn, err := jump.Dial("tcp", options.Addr)
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(conn, options.Addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// signers collects the keys to authenticate with: the inline key, the key
+// files and, if available, the keys held by the SSH agent. The returned func
+// closes the connection to the agent once the handshake is done.
+func signers(options *NewOptions) ([]ssh.Signer, func(), error) {
+	signers := []ssh.Signer{}
 	if len(options.Key) > 0 {
 		signer, err := ssh.ParsePrivateKey([]byte(options.Key))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-
-		auth = append(auth, ssh.PublicKeys(signer))
+		signers = append(signers, signer)
 	} else if len(options.KeyPath) > 0 {
 		key, err := os.ReadFile(options.KeyPath)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		signers = append(signers, signer)
+	}
 
+	// Identity files come from the configuration or the defaults, so missing
+	// or passphrase protected ones are skipped like `ssh` does.
+	for _, identityFile := range options.IdentityFiles {
+		key, err := os.ReadFile(identityFile)
+		if err != nil {
+			continue
+		}
 		signer, err := ssh.ParsePrivateKey(key)
 		if err != nil {
-			return nil, err
+			continue
 		}
+		signers = append(signers, signer)
+	}
 
-		auth = append(auth, ssh.PublicKeys(signer))
+	socket := options.AgentSocket
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
 	}
-	if len(options.Password) > 0 {
-		auth = append(auth, ssh.Password(options.Password))
+	if socket == "" {
+		return signers, func() {}, nil
 	}
-
-	client, err := ssh.Dial("tcp", options.Addr, &ssh.ClientConfig{
-		User:    options.User,
-		Auth:    auth,
-		Timeout: time.Duration(options.Timeout) * time.Second,
-		HostKeyCallback: (func() ssh.HostKeyCallback {
-			if options.Insecure {
-				return ssh.InsecureIgnoreHostKey()
-			}
-			if callback, err := knownhosts.New(options.KnownHosts...); err == nil {
-				return callback
-			} else {
-				return nil
-			}
-		})(),
-	})
+	conn, err := net.Dial("unix", socket)
 	if err != nil {
-		return nil, err
+		return signers, func() {}, nil
 	}
-
-	s := &SshClient{client: client, uploaded: &sync.Once{}}
-	err = s.Run(&RunOptions{Commands: []string{""}})
+	agentSigners, err := agent.NewClient(conn).Signers()
 	if err != nil {
-		return nil, err
+		conn.Close()
+		return signers, func() {}, nil
 	}
-	return s, nil
+	return append(signers, agentSigners...), func() { conn.Close() }, nil
 }
 
 type RunOptions struct {
@@ -88,9 +201,27 @@ type RunOptions struct {
 	Stdin    io.Reader
 	Stdout   io.Writer
 	Stderr   io.Writer
+	// Tty requests a pseudo terminal for the commands, making them interactive.
+	// Stdin is then forwarded to them.
+	Tty *TtyOptions
+}
+
+type TtyOptions struct {
+	// Term is the terminal type, as in the TERM variable.
+	Term string
+	// Size returns the width and height of the terminal.
+	Size func() (int, int, error)
+	// Resize receives a value whenever the terminal is resized.
+	Resize <-chan struct{}
 }
 
-func (s *SshClient) Run(options *RunOptions) error {
+// Run runs the commands on the host with its default shell. A failing command
+// returns a *errors.TaskSSHCommandError carrying the remote exit code.
+//
+// When the context is cancelled, the commands are sent SIGINT, then SIGTERM
+// and SIGKILL if they keep running, before the session is closed, which is
+// all hosts ignoring signal requests get.
+func (s *SshClient) Run(ctx context.Context, options *RunOptions) error {
 	session, err := s.client.NewSession()
 	if err != nil {
 		return err
@@ -105,104 +236,166 @@ func (s *SshClient) Run(options *RunOptions) error {
 
 	session.Stdout = options.Stdout
 	session.Stderr = options.Stderr
-
-	writer, err := session.StdinPipe()
-	if err != nil {
-		return err
+	if options.Tty != nil {
+		stop, err := requestTty(session, options)
+		if err != nil {
+			return err
+		}
+		defer stop()
 	}
-	defer writer.Close()
 
-	err = session.Shell()
-	if err != nil {
-		return err
+	command := strings.Join(options.Commands, "\n")
+	if err := session.Start(command); err != nil {
+		return s.commandError(command, err)
 	}
+	done := make(chan error, 1)
+	go func() { done <- session.Wait() }()
 
-	cmds := append(options.Commands, "\x00")
-	g := errgroup.Group{}
-	g.Go(func() error {
-		defer writer.Close()
-		for _, cmd := range cmds {
-			_, err := fmt.Fprintf(writer, "%s\n", cmd)
-			if err != nil {
-				return err
-			}
+	select {
+	case err := <-done:
+		if err != nil {
+			return s.commandError(command, err)
 		}
 		return nil
-	})
-	g.Go(func() error {
-		return session.Wait()
-	})
-
-	return g.Wait()
-}
-
-type UploadOnceOptions = []struct {
-	Source string
-	Target string
-}
-
-func (s *SshClient) UploadOnce(options UploadOnceOptions) error {
-	var err error
-	s.uploaded.Do(func() {
-		for _, upload := range options {
-			err = s.upload(upload.Source, upload.Target)
+	case <-ctx.Done():
+	}
+	for _, signal := range cancelSignals {
+		if err := session.Signal(signal); err != nil {
+			break
+		}
+		select {
+		case err := <-done:
 			if err != nil {
-				return
+				return s.commandError(command, err)
 			}
+			return nil
+		case <-time.After(s.signalTimeout):
 		}
-	})
-	return err
+	}
+	session.Close()
+	return ctx.Err()
 }
 
-func (s *SshClient) upload(source string, target string) error {
-	f, err := os.Open(source)
+// requestTty allocates a pseudo terminal for the session and keeps its size in
+// sync with the local terminal until the returned func is called.
+func requestTty(session *ssh.Session, options *RunOptions) (func(), error) {
+	tty := options.Tty
+	width, height, err := tty.Size()
 	if err != nil {
-		return err
+		width, height = 80, 24
 	}
-	defer f.Close()
-
-	stat, err := f.Stat()
-	if err != nil {
-		return err
+	term := tty.Term
+	if term == "" {
+		term = "xterm"
 	}
-
-	session, err := s.client.NewSession()
-	if err != nil {
-		return err
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
 	}
-	defer session.Close()
-
-	writer, err := session.StdinPipe()
-	if err != nil {
-		return err
+	if err := session.RequestPty(term, height, width, modes); err != nil {
+		return nil, fmt.Errorf("ssh: unable to allocate a terminal: %w", err)
 	}
-	defer writer.Close()
 
-	err = session.Start(fmt.Sprintf("%s -qt %q", "scp", target))
-	if err != nil {
-		return err
-	}
-	g := errgroup.Group{}
-	g.Go(func() error {
-		defer writer.Close()
-		_, err := fmt.Fprintln(writer, fmt.Sprintf("C%04o", stat.Mode().Perm()), stat.Size(), path.Base(target))
+	// The input is copied without waiting for it to end, as it usually doesn't
+	// when the commands exit
+	if options.Stdin != nil {
+		stdin, err := session.StdinPipe()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		_, err = io.Copy(writer, f)
-		if err != nil {
-			return err
+		go func() {
+			_, _ = io.Copy(stdin, options.Stdin)
+			stdin.Close()
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-tty.Resize:
+				if width, height, err := tty.Size(); err == nil {
+					_ = session.WindowChange(height, width)
+				}
+			case <-done:
+				return
+			}
 		}
-		_, err = fmt.Fprint(writer, "\x00")
+	}()
+	return func() { close(done) }, nil
+}
+
+// commandError maps the error of a remote command to the exit status the
+// same command would have had locally: its exit code, 128 plus the signal
+// number when it was killed, and 255 when the connection was lost, like the
+// OpenSSH client does.
+func (s *SshClient) commandError(command string, err error) error {
+	var exitErr *ssh.ExitError
+	var missingErr *ssh.ExitMissingError
+	switch {
+	case errors.As(err, &exitErr):
+		if signal := exitErr.Signal(); signal != "" {
+			return &errors.TaskSSHCommandError{
+				Host:     s.addr,
+				Command:  command,
+				ExitCode: 128 + signalNumber(signal),
+				Signal:   signal,
+			}
+		}
+		return &errors.TaskSSHCommandError{
+			Host:     s.addr,
+			Command:  command,
+			ExitCode: exitErr.ExitStatus(),
+		}
+	case errors.As(err, &missingErr), errors.Is(err, io.EOF), errors.Is(err, net.ErrClosed):
+		return &errors.TaskSSHCommandError{
+			Host:     s.addr,
+			Command:  command,
+			ExitCode: 255,
+			Err:      err,
+		}
+	default:
 		return err
-	})
-	g.Go(func() error {
-		return session.Wait()
-	})
+	}
+}
 
-	return g.Wait()
+// signalNumbers are the POSIX numbers of the signals defined by RFC 4254.
+var signalNumbers = map[string]int{
+	"HUP":  1,
+	"INT":  2,
+	"QUIT": 3,
+	"ILL":  4,
+	"ABRT": 6,
+	"FPE":  8,
+	"KILL": 9,
+	"USR1": 10,
+	"SEGV": 11,
+	"USR2": 12,
+	"PIPE": 13,
+	"ALRM": 14,
+	"TERM": 15,
+}
+
+func signalNumber(signal string) int {
+	return signalNumbers[strings.TrimPrefix(signal, "SIG")]
+}
+
+// Addr returns the resolved address of the host.
+func (s *SshClient) Addr() string {
+	return s.addr
 }
 
 func (s *SshClient) Close() error {
-	return s.client.Close()
+	var errs []error
+	if s.sftp != nil {
+		errs = append(errs, s.sftp.Close())
+	}
+	if s.client != nil {
+		errs = append(errs, s.client.Close())
+	}
+	for i := len(s.jumps) - 1; i >= 0; i-- {
+		errs = append(errs, s.jumps[i].Close())
+	}
+	return errors.Join(errs...)
 }
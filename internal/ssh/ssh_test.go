@@ -0,0 +1,308 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"mvdan.cc/sh/v3/interp"
+
+	"github.com/go-task/task/v3/errors"
+)
+
+func newTestKey(t *testing.T) (ed25519.PrivateKey, ssh.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(pub)
+	require.NoError(t, err)
+	return priv, sshPub
+}
+
+func writeTestKey(t *testing.T, priv ed25519.PrivateKey) string {
+	t.Helper()
+
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "id_ed25519")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0o600))
+	return path
+}
+
+func runEcho(t *testing.T, client *SshClient) {
+	t.Helper()
+
+	var stdout bytes.Buffer
+	require.NoError(t, client.Run(t.Context(), &RunOptions{Commands: []string{"echo foo"}, Stdout: &stdout}))
+	assert.Equal(t, "foo\n", stdout.String())
+}
+
+func TestAgent(t *testing.T) {
+	t.Parallel()
+
+	priv, pub := newTestKey(t)
+	server := newTestServer(t, pub)
+
+	keyring := agent.NewKeyring()
+	require.NoError(t, keyring.Add(agent.AddedKey{PrivateKey: priv}))
+	socket := filepath.Join(t.TempDir(), "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_ = agent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	client, err := NewSshClient(&NewOptions{
+		Addr:        server.addr,
+		User:        testUser,
+		Insecure:    true,
+		ConfigFile:  os.DevNull,
+		AgentSocket: socket,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	runEcho(t, client)
+}
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	priv, pub := newTestKey(t)
+	server := newTestServer(t, pub)
+	host, port, err := net.SplitHostPort(server.addr)
+	require.NoError(t, err)
+
+	config := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(config, fmt.Appendf(nil, `
+Host target
+  HostName %s
+  Port %s
+  User %s
+  IdentityFile %s
+`, host, port, testUser, writeTestKey(t, priv)), 0o600))
+
+	client, err := NewSshClient(&NewOptions{
+		Addr:       "target",
+		Insecure:   true,
+		ConfigFile: config,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	runEcho(t, client)
+}
+
+func TestJump(t *testing.T) {
+	t.Parallel()
+
+	bastion1 := newTestServer(t)
+	bastion2 := newTestServer(t)
+	target := newTestServer(t)
+
+	client, err := NewSshClient(&NewOptions{
+		Addr:       target.addr,
+		User:       testUser,
+		Password:   testPassword,
+		Insecure:   true,
+		ConfigFile: os.DevNull,
+		Jump:       []*NewOptions{bastion1.options(), bastion2.options()},
+	})
+	require.NoError(t, err)
+	runEcho(t, client)
+	require.NoError(t, client.Close())
+
+	assert.Equal(t, int32(1), bastion1.forwards.Load())
+	assert.Equal(t, int32(1), bastion2.forwards.Load())
+	assert.Equal(t, int32(1), target.conns.Load())
+}
+
+func TestProxyJump(t *testing.T) {
+	t.Parallel()
+
+	bastion := newTestServer(t)
+	target := newTestServer(t)
+	host, port, err := net.SplitHostPort(target.addr)
+	require.NoError(t, err)
+
+	config := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(config, fmt.Appendf(nil, `
+Host target
+  HostName %s
+  Port %s
+  ProxyJump %s:%s@%s
+`, host, port, testUser, testPassword, bastion.addr), 0o600))
+
+	client, err := NewSshClient(&NewOptions{
+		Addr:       "target",
+		User:       testUser,
+		Password:   testPassword,
+		Insecure:   true,
+		ConfigFile: config,
+	})
+	require.NoError(t, err)
+	defer client.Close()
+	runEcho(t, client)
+
+	assert.Equal(t, int32(1), bastion.forwards.Load())
+}
+
+func TestRunExitStatus(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	tests := []struct {
+		name     string
+		command  string
+		exitCode int
+		signal   string
+	}{
+		{name: "success", command: "true"},
+		{name: "exit code", command: "exit 3", exitCode: 3},
+		{name: "last command", command: "false\ntrue"},
+		{name: "signal", command: "kill -TERM $$", exitCode: 143, signal: "TERM"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := client.Run(t.Context(), &RunOptions{Commands: []string{test.command}})
+			if test.exitCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var commandErr *errors.TaskSSHCommandError
+			require.True(t, errors.As(err, &commandErr), "unexpected error: %v", err)
+			assert.Equal(t, server.addr, commandErr.Host)
+			assert.Equal(t, test.command, commandErr.Command)
+			assert.Equal(t, test.exitCode, commandErr.ExitCode)
+			assert.Equal(t, test.signal, commandErr.Signal)
+
+			var exitStatus interp.ExitStatus
+			require.True(t, errors.As(err, &exitStatus))
+			assert.Equal(t, interp.ExitStatus(test.exitCode), exitStatus)
+		})
+	}
+}
+
+func TestRunConnectionLost(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		client.client.Close()
+	}()
+	err = client.Run(t.Context(), &RunOptions{Commands: []string{"sleep 5"}})
+
+	var commandErr *errors.TaskSSHCommandError
+	require.True(t, errors.As(err, &commandErr), "unexpected error: %v", err)
+	assert.Equal(t, 255, commandErr.ExitCode)
+	assert.Error(t, commandErr.Err)
+}
+
+func TestRunTty(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	var width atomic.Int32
+	width.Store(80)
+	resize := make(chan struct{})
+	stdin, input := io.Pipe()
+	var stdout bytes.Buffer
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Run(t.Context(), &RunOptions{
+			Commands: []string{`read line; echo "$TERM $line"`},
+			Stdin:    stdin,
+			Stdout:   &stdout,
+			Tty: &TtyOptions{
+				Term:   "xterm-test",
+				Size:   func() (int, int, error) { return int(width.Load()), 24, nil },
+				Resize: resize,
+			},
+		})
+	}()
+
+	require.Eventually(t, func() bool { return len(server.ttys()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	width.Store(120)
+	resize <- struct{}{}
+	require.Eventually(t, func() bool { return len(server.ttys()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	_, err = input.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	require.NoError(t, <-done)
+	assert.Equal(t, "xterm-test hello\n", stdout.String())
+	assert.Equal(t, []string{"pty-req xterm-test 80x24", "window-change 120x24"}, server.ttys())
+}
+
+func TestRunCancel(t *testing.T) {
+	t.Parallel()
+
+	server := newTestServer(t)
+	client, err := NewSshClient(server.options())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	client.signalTimeout = 200 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		command  string
+		exitCode int
+		signal   string
+	}{
+		{name: "interrupt", command: "sleep 5", exitCode: 130, signal: "INT"},
+		{name: "terminate", command: "trap '' INT; sleep 5", exitCode: 143, signal: "TERM"},
+		{name: "kill", command: "trap '' INT TERM; sleep 5", exitCode: 137, signal: "KILL"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			err := client.Run(ctx, &RunOptions{Commands: []string{test.command}})
+			assert.Less(t, time.Since(start), 3*time.Second)
+
+			var commandErr *errors.TaskSSHCommandError
+			require.True(t, errors.As(err, &commandErr), "unexpected error: %v", err)
+			assert.Equal(t, test.exitCode, commandErr.ExitCode)
+			assert.Equal(t, test.signal, commandErr.Signal)
+		})
+	}
+}
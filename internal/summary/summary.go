@@ -113,6 +113,8 @@ func printTaskCommands(l *logger.Logger, t *ast.Task) {
 		l.Outf(logger.Default, " - ")
 		if isCommand {
 			l.Outf(logger.Yellow, "%s\n", c.Cmd)
+		} else if c.Plugin != "" {
+			l.Outf(logger.Green, "Plugin: %s\n", c.Plugin)
 		} else {
 			l.Outf(logger.Green, "Task: %s\n", c.Task)
 		}
@@ -2,6 +2,7 @@ package templater
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"maps"
 	"strings"
@@ -18,6 +19,9 @@ import (
 // return the zero value.
 type Cache struct {
 	Vars *ast.Vars
+	// Ctx is given to the plugin functions the templates call, so that they
+	// write to its output and stop with it. It defaults to the background.
+	Ctx context.Context
 
 	cacheMap map[string]any
 	err      error
@@ -31,6 +35,13 @@ func (r *Cache) Err() error {
 	return r.err
 }
 
+func (r *Cache) context() context.Context {
+	if r.Ctx == nil {
+		return context.Background()
+	}
+	return r.Ctx
+}
+
 func ResolveRef(ref string, cache *Cache) any {
 	// If there is already an error, do nothing
 	if cache.err != nil {
@@ -42,16 +53,10 @@ func ResolveRef(ref string, cache *Cache) any {
 		cache.cacheMap = cache.Vars.ToCacheMap()
 	}
 
-	templatePluginFuncs := template.FuncMap{}
-	templatePluginFuncsSync.Range(func(key, value any) bool {
-		templatePluginFuncs[key.(string)] = value
-		return true
-	})
-
 	if ref == "." {
 		return cache.cacheMap
 	}
-	t, err := template.New("resolver").Funcs(templateFuncs).Funcs(templatePluginFuncs).Parse(fmt.Sprintf("{{%s}}", ref))
+	t, err := template.New("resolver").Funcs(templateFuncs).Funcs(pluginFuncs(cache.context(), cache.cacheMap)).Parse(fmt.Sprintf("{{%s}}", ref))
 	if err != nil {
 		cache.err = err
 		return nil
@@ -86,11 +91,7 @@ func ReplaceWithExtra[T any](v T, cache *Cache, extra map[string]any) T {
 		maps.Copy(data, extra)
 	}
 
-	templatePluginFuncs := template.FuncMap{}
-	templatePluginFuncsSync.Range(func(key, value any) bool {
-		templatePluginFuncs[key.(string)] = value
-		return true
-	})
+	templatePluginFuncs := pluginFuncs(cache.context(), data)
 
 	// Traverse the value and parse any template variables
 	copy, err := deepcopy.TraverseStringsFunc(v, func(v string) (string, error) {
@@ -142,6 +143,9 @@ func ReplaceVarWithExtra(v ast.Var, cache *Cache, extra map[string]any) ast.Var
 		Live:   v.Live,
 		Ref:    v.Ref,
 		Dir:    v.Dir,
+		Plugin: ReplaceWithExtra(v.Plugin, cache, extra),
+		Input:  ReplaceWithExtra(v.Input, cache, extra),
+		Logs:   v.Logs,
 	}
 }
 
@@ -162,6 +166,23 @@ func ReplaceVarsWithExtra(vars *ast.Vars, cache *Cache, extra map[string]any) *a
 	return newVars
 }
 
-func ExposePluginFunc(name string, fn func(input string) any) {
+// PluginFunc is a template function provided by a plugin. It is given the
+// context and the variables of the template calling it, and the error it
+// returns aborts the rendering of the template.
+type PluginFunc func(ctx context.Context, vars map[string]any, args ...any) (any, error)
+
+func ExposePluginFunc(name string, fn PluginFunc) {
 	templatePluginFuncsSync.Store(name, fn)
 }
+
+// pluginFuncs returns the plugin functions bound to the context and the
+// variables of the template being rendered.
+func pluginFuncs(ctx context.Context, vars map[string]any) template.FuncMap {
+	funcs := template.FuncMap{}
+	templatePluginFuncsSync.Range(func(key, value any) bool {
+		fn := value.(PluginFunc)
+		funcs[key.(string)] = func(args ...any) (any, error) { return fn(ctx, vars, args...) }
+		return true
+	})
+	return funcs
+}
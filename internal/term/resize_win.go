@@ -0,0 +1,35 @@
+//go:build windows
+
+package term
+
+import "time"
+
+// NotifyResize sends to the channel whenever the terminal is resized, without
+// blocking, until the returned func is called.
+//
+// NOTE: Windows has no resize signal, so the size is polled instead.
+func NotifyResize(c chan<- struct{}) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(250 * time.Millisecond)
+		defer ticker.Stop()
+		width, height, _ := Size()
+		for {
+			select {
+			case <-ticker.C:
+				w, h, err := Size()
+				if err != nil || (w == width && h == height) {
+					continue
+				}
+				width, height = w, h
+				select {
+				case c <- struct{}{}:
+				default:
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
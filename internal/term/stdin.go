@@ -0,0 +1,31 @@
+//go:build !windows
+
+package term
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// Stdin returns a reader of the standard input whose pending reads are
+// interrupted when the returned func is called. This keeps a goroutine
+// copying the input from consuming keys after it is no longer needed.
+func Stdin() (io.Reader, func()) {
+	fd, err := syscall.Dup(int(os.Stdin.Fd()))
+	if err != nil {
+		return os.Stdin, func() {}
+	}
+	// A non-blocking descriptor is handled by the runtime poller, which lets
+	// Close interrupt a pending read
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return os.Stdin, func() {}
+	}
+	f := os.NewFile(uintptr(fd), "/dev/stdin")
+	return f, func() {
+		f.Close()
+		// The flag is shared with the original descriptor
+		_ = syscall.SetNonblock(int(os.Stdin.Fd()), false)
+	}
+}
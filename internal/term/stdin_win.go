@@ -0,0 +1,16 @@
+//go:build windows
+
+package term
+
+import (
+	"io"
+	"os"
+)
+
+// Stdin returns a reader of the standard input whose pending reads are
+// interrupted when the returned func is called.
+//
+// NOTE: Reads can't be interrupted on Windows, so this is os.Stdin as is.
+func Stdin() (io.Reader, func()) {
+	return os.Stdin, func() {}
+}
@@ -9,3 +9,19 @@ import (
 func IsTerminal() bool {
 	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
 }
+
+// Size returns the width and height of the terminal.
+func Size() (int, int, error) {
+	return term.GetSize(int(os.Stdout.Fd()))
+}
+
+// MakeRaw puts the terminal in raw mode, so that keys, Ctrl-C included, are
+// read as is. The returned func restores its previous state.
+func MakeRaw() (func() error, error) {
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	return func() error { return term.Restore(fd, state) }, nil
+}
@@ -0,0 +1,77 @@
+package wasmext
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/tetratelabs/wazero/experimental"
+)
+
+// MaxMemory caps the size of each memory of the modules instantiated with its
+// context. Unlike the memory limit of the runtime, it records when a memory
+// fails to grow past the cap: modules then trap on their own, which can't be
+// told apart from their other traps otherwise.
+type MaxMemory struct {
+	limit    uint64
+	exceeded atomic.Bool
+}
+
+// NewMaxMemory returns a cap of limit bytes, or nil when limit is 0, in which
+// case memories aren't capped.
+func NewMaxMemory(limit uint64) *MaxMemory {
+	if limit == 0 {
+		return nil
+	}
+	return &MaxMemory{limit: limit}
+}
+
+// WithContext returns a context which instantiates modules with their memories
+// capped.
+func (m *MaxMemory) WithContext(ctx context.Context) context.Context {
+	if m == nil {
+		return ctx
+	}
+	return experimental.WithMemoryAllocator(ctx, experimental.MemoryAllocatorFunc(func(capacity, _ uint64) experimental.LinearMemory {
+		return &linearMemory{max: m, buf: make([]byte, 0, min(capacity, m.limit))}
+	}))
+}
+
+// Exceeded reports whether a memory failed to grow past the cap since the last
+// call to Reset, or was instantiated past it.
+func (m *MaxMemory) Exceeded() bool {
+	return m != nil && m.exceeded.Load()
+}
+
+// Reset forgets about the memories which exceeded the cap, before a new call.
+func (m *MaxMemory) Reset() {
+	if m != nil {
+		m.exceeded.Store(false)
+	}
+}
+
+// linearMemory is a memory which refuses to grow past its cap. Its initial size
+// can't be refused, so a memory instantiated past the cap is only recorded as
+// exceeding it.
+type linearMemory struct {
+	max       *MaxMemory
+	buf       []byte
+	allocated bool
+}
+
+func (l *linearMemory) Reallocate(size uint64) []byte {
+	if size > l.max.limit {
+		l.max.exceeded.Store(true)
+		if l.allocated {
+			return nil
+		}
+	}
+	l.allocated = true
+	if n := uint64(len(l.buf)); size > n {
+		l.buf = append(l.buf, make([]byte, size-n)...)
+	}
+	return l.buf[:size]
+}
+
+func (l *linearMemory) Free() {
+	l.buf = nil
+}
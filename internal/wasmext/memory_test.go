@@ -0,0 +1,31 @@
+package wasmext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	m := NewMaxMemory(4)
+	memory := &linearMemory{max: m}
+	assert.Len(t, memory.Reallocate(2), 2)
+	assert.Len(t, memory.Reallocate(4), 4)
+	assert.False(t, m.Exceeded())
+
+	assert.Nil(t, memory.Reallocate(8))
+	assert.True(t, m.Exceeded())
+	m.Reset()
+	assert.False(t, m.Exceeded())
+
+	// The initial size of a memory can't be refused
+	assert.Len(t, (&linearMemory{max: m}).Reallocate(8), 8)
+	assert.True(t, m.Exceeded())
+
+	var uncapped *MaxMemory
+	assert.Nil(t, NewMaxMemory(0))
+	assert.False(t, uncapped.Exceeded())
+	assert.Equal(t, t.Context(), uncapped.WithContext(t.Context()))
+}
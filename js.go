@@ -0,0 +1,99 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/execext"
+	"github.com/go-task/task/v3/internal/filepathext"
+	taskJs "github.com/go-task/task/v3/internal/js"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// jsSandbox returns the sandbox of the scripts of the task run in dir, which
+// is the one of its interp_options, or else of the ones of the main Taskfile.
+// A nil task is the one of the variables of the Taskfiles. Included and remote
+// Taskfiles can't be trusted to widen the sandbox, so their tasks can only
+// narrow the one of the main Taskfile.
+func (c *Compiler) jsSandbox(t *ast.Task, dir string) *taskJs.Sandbox {
+	if t == nil || t.InterpOptions == nil {
+		return jsSandbox(c.InterpOptions, dir)
+	}
+	sandbox := jsSandbox(t.InterpOptions, dir)
+	if t.Location != nil && t.Location.Taskfile != c.RootTaskfile {
+		sandbox = sandbox.Restrict(jsSandbox(c.InterpOptions, dir))
+	}
+	return sandbox
+}
+
+// jsSandbox returns the sandbox of the scripts run in dir with the given
+// interp_options.
+func jsSandbox(options *ast.InterpOptions, dir string) *taskJs.Sandbox {
+	sandbox := taskJs.DefaultSandbox(dir)
+	if options == nil {
+		return sandbox
+	}
+	if options.Mounts != nil {
+		sandbox.Mounts = make(map[string]string, len(options.Mounts))
+		for host, guest := range options.Mounts {
+			sandbox.Mounts[filepathext.SmartJoin(dir, host)] = guest
+		}
+	}
+	sandbox.ReadOnly = options.ReadOnly
+	sandbox.MaxMemory = options.MaxMemory
+	sandbox.Network = options.Network
+	sandbox.Timeout = options.Timeout
+	sandbox.HideEnv = !options.EnvVisible()
+	sandbox.Exec = options.ExecAllowed()
+	sandbox.Run = options.RunAllowed()
+	return sandbox
+}
+
+// jsHost exposes the executor to the task global of JavaScript commands.
+type jsHost struct {
+	e       *Executor
+	ctx     context.Context
+	t       *ast.Task
+	sandbox *taskJs.Sandbox
+	stderr  io.Writer
+}
+
+// RunTask runs the task if interp_options allow it.
+func (h *jsHost) RunTask(name string, vars map[string]any) error {
+	if !h.sandbox.Run {
+		return errors.New("task.run is not allowed, it is disabled by the interp_options of the task or of the main Taskfile")
+	}
+	return (&pluginHost{h.e}).RunTask(h.ctx, name, vars)
+}
+
+// Log writes the message to the stderr of the task, so that it goes through
+// the same output as the task.
+func (h *jsHost) Log(message string, warn bool) {
+	color := logger.Default
+	if warn {
+		color = logger.Yellow
+	}
+	h.e.Logger.FOutf(h.stderr, color, "%s\n", message)
+}
+
+// Exec runs the command the way the commands of the task are run, if
+// interp_options allow it.
+func (h *jsHost) Exec(command string) (string, error) {
+	if !h.sandbox.Exec {
+		return "", errors.New("task.exec is not allowed, it is disabled by the interp_options of the task or of the main Taskfile")
+	}
+	var stdout bytes.Buffer
+	err := execext.RunCommand(h.ctx, &execext.RunCommandOptions{
+		Command:   command,
+		Dir:       h.t.Dir,
+		Env:       env.Get(h.t),
+		Stdout:    &stdout,
+		Stderr:    h.stderr,
+		JSSandbox: h.sandbox,
+	})
+	return stdout.String(), err
+}
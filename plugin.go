@@ -0,0 +1,226 @@
+package task
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/experiments"
+	"github.com/go-task/task/v3/internal/logger"
+	"github.com/go-task/task/v3/taskfile"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// pluginHost exposes the executor to the host functions of plugins.
+type pluginHost struct {
+	e *Executor
+}
+
+// Log writes the message to the stderr of the task calling the plugin, so
+// that it goes through the same output as the task.
+func (h *pluginHost) Log(ctx context.Context, level taskfile.PluginLogLevel, message string) {
+	w, ok := taskfile.PluginStderr(ctx)
+	if !ok {
+		w = h.e.Logger.Stderr
+	}
+	switch level {
+	case taskfile.PluginLogVerbose:
+		if h.e.Logger.Verbose {
+			h.e.Logger.FOutf(w, logger.Magenta, "%s\n", message)
+		}
+	case taskfile.PluginLogWarning:
+		h.e.Logger.FOutf(w, logger.Yellow, "%s\n", message)
+	case taskfile.PluginLogError:
+		h.e.Logger.FOutf(w, logger.Red, "%s\n", message)
+	default:
+		h.e.Logger.FOutf(w, logger.Default, "%s\n", message)
+	}
+}
+
+type pluginEnvKey struct{}
+
+// withPluginEnv attaches the compiled env of the task calling a plugin to the
+// context of the call, where task_env reads it.
+func withPluginEnv(ctx context.Context, env *ast.Vars) context.Context {
+	return context.WithValue(ctx, pluginEnvKey{}, env)
+}
+
+// LookupEnv follows the precedence tasks use between the environment of the
+// process and the env of the task calling the plugin. Calls made before the
+// env of the task is compiled, as for its variables, see the Taskfile env,
+// dotenv files included.
+func (h *pluginHost) LookupEnv(ctx context.Context, name string) (string, bool) {
+	value, inEnviron := os.LookupEnv(name)
+	if inEnviron && !experiments.EnvPrecedence.Enabled() {
+		return value, true
+	}
+	if env, ok := ctx.Value(pluginEnvKey{}).(*ast.Vars); ok {
+		if v, ok := env.Get(name); ok && v.Value != nil {
+			return fmt.Sprint(v.Value), true
+		}
+		return value, inEnviron
+	}
+	if h.e.Taskfile == nil || h.e.Compiler == nil {
+		return value, inEnviron
+	}
+	if _, ok := h.e.Taskfile.Env.Get(name); !ok {
+		return value, inEnviron
+	}
+	vars, err := h.e.Compiler.GetTaskfileVariables()
+	if err != nil {
+		return value, inEnviron
+	}
+	if v, ok := vars.Get(name); ok && v.Value != nil {
+		return fmt.Sprint(v.Value), true
+	}
+	return value, inEnviron
+}
+
+func (h *pluginHost) RunTask(ctx context.Context, name string, vars map[string]any) error {
+	call := &Call{Task: name, Vars: ast.NewVars(), Indirect: true}
+	for k, v := range vars {
+		call.Vars.Set(k, ast.Var{Value: v})
+	}
+	return h.e.RunTask(ctx, call)
+}
+
+// The lifecycle hooks plugins may export
+const (
+	pluginBeforeTaskHook = "before_task"
+	pluginAfterTaskHook  = "after_task"
+	pluginOnErrorHook    = "on_error"
+)
+
+// pluginTaskEvent describes a task to the lifecycle hooks of plugins. The
+// duration, error and exit code are set once the task ran.
+type pluginTaskEvent struct {
+	Hook     string         `json:"hook"`
+	Task     string         `json:"task"`
+	Vars     map[string]any `json:"vars"`
+	Dir      string         `json:"dir"`
+	Duration float64        `json:"duration"`
+	Error    string         `json:"error,omitempty"`
+	ExitCode int            `json:"exit_code"`
+}
+
+// runWithPluginHooks runs the task between the lifecycle hooks of the plugins:
+// before_task, which fails the task when it fails, then on_error if the task
+// failed and after_task in any case. Hooks failing once the task ran fail it
+// too, along with the error of the task if it failed.
+func (e *Executor) runWithPluginHooks(ctx context.Context, t *ast.Task, run func(ctx context.Context) error) error {
+	if len(e.plugins) == 0 || e.Dry {
+		return run(ctx)
+	}
+
+	ctx = withPluginEnv(ctx, t.Env)
+	event := &pluginTaskEvent{
+		Task: t.Name(),
+		Vars: t.Vars.ToCacheMap(),
+		Dir:  t.Dir,
+	}
+	if err := e.runPluginHooks(ctx, pluginBeforeTaskHook, event); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err := run(ctx)
+	event.Duration = time.Since(start).Seconds()
+
+	// Hooks still run once the task was interrupted
+	ctx = context.WithoutCancel(ctx)
+	if err != nil {
+		event.Error = err.Error()
+		event.ExitCode = taskExitCode(err)
+		if hookErr := e.runPluginHooks(ctx, pluginOnErrorHook, event); hookErr != nil {
+			err = errors.Join(err, hookErr)
+		}
+	}
+	if hookErr := e.runPluginHooks(ctx, pluginAfterTaskHook, event); hookErr != nil {
+		if err != nil {
+			return errors.Join(err, hookErr)
+		}
+		return hookErr
+	}
+	return err
+}
+
+// runPluginHooks calls the hook of every plugin exporting it, in the order the
+// plugins are declared.
+func (e *Executor) runPluginHooks(ctx context.Context, hook string, event *pluginTaskEvent) error {
+	event.Hook = hook
+	input, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	for name, plugin := range e.Taskfile.Plugins.All() {
+		if !e.plugins.Has(name, hook) {
+			continue
+		}
+		if _, err := e.plugins.Call(taskfile.WithPluginOutput(ctx, e.Stdout, e.Stderr), name, hook, event.Vars, input); err != nil {
+			if plugin.HookFailure == ast.PluginHookFailureWarn {
+				e.Logger.Errf(logger.Yellow, "%v\n", err)
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// taskExitCode returns the exit code Task would exit with because of the error.
+func taskExitCode(err error) int {
+	var runErr *errors.TaskRunError
+	if errors.As(err, &runErr) {
+		return runErr.TaskExitCode()
+	}
+	var taskErr errors.TaskError
+	if errors.As(err, &taskErr) {
+		return taskErr.Code()
+	}
+	return 1
+}
+
+// runPluginCommand calls the export of a plugin: command, and writes its
+// output to the output of the task.
+func (e *Executor) runPluginCommand(ctx context.Context, t *ast.Task, cmd *ast.Cmd, vars *ast.Vars, stdOut, stdErr io.Writer) error {
+	pluginName, export, _ := strings.Cut(cmd.Plugin, ".")
+	switch {
+	case !e.plugins.Has(pluginName, export):
+		return fmt.Errorf(`task: invalid plugin call "%s": plugin %q has no export %q`, cmd.Plugin, pluginName, export)
+	case t.SshClient != nil:
+		return fmt.Errorf(`task: plugin call "%s" is not supported by tasks running on an SSH host`, cmd.Plugin)
+	}
+	input, err := pluginCmdInput(cmd.Input)
+	if err != nil {
+		return fmt.Errorf("task: Plugin %q: invalid input for %q: %w", pluginName, export, err)
+	}
+	ctx = withPluginEnv(taskfile.WithPluginOutput(ctx, stdOut, stdErr), t.Env)
+	out, err := e.plugins.Call(ctx, pluginName, export, vars.ToCacheMap(), input)
+	if err != nil || len(out) == 0 {
+		return err
+	}
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	_, err = stdOut.Write(out)
+	return err
+}
+
+// pluginCmdInput encodes the input of a plugin: command. A string is passed as
+// is and any other value as JSON.
+func pluginCmdInput(input any) ([]byte, error) {
+	switch input := input.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []byte(input), nil
+	default:
+		return json.Marshal(input)
+	}
+}
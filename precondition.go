@@ -17,12 +17,18 @@ var ErrPreconditionFailed = errors.New("task: precondition not met")
 func (e *Executor) areTaskPreconditionsMet(ctx context.Context, t *ast.Task) (bool, error) {
 	for _, p := range t.Preconditions {
 		if t.SshClient != nil {
-			err := t.SshClient.Run(&taskSsh.RunOptions{
+			err := t.SshClient.Run(ctx, &taskSsh.RunOptions{
 				Commands: []string{p.Sh},
 				Env:      env.GetMap(t, false),
 			})
 			if err != nil {
-				// TODO logging
+				var commandErr *errors.TaskSSHCommandError
+				if !errors.As(err, &commandErr) || commandErr.Err != nil {
+					return false, err
+				}
+				if ctx.Err() == nil {
+					e.Logger.Errf(logger.Magenta, "task: %s\n", p.Msg)
+				}
 				return false, ErrPreconditionFailed
 			}
 			continue
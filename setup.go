@@ -81,13 +81,9 @@ func (e *Executor) readTaskfile(node taskfile.Node) error {
 		taskfile.WithCacheExpiryDuration(e.CacheExpiryDuration),
 		taskfile.WithDebugFunc(debugFunc),
 		taskfile.WithPromptFunc(promptFunc),
+		taskfile.WithPluginHost(&pluginHost{e: e}),
 	)
 	graph, err := reader.Read(ctx, node)
-	if experiments.Plugins.Enabled() {
-		if err := reader.LoadPlugin(ctx, node); err != nil {
-			return err
-		}
-	}
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
 			return &errors.TaskfileNetworkTimeoutError{URI: node.Location(), Timeout: e.Timeout}
@@ -97,6 +93,11 @@ func (e *Executor) readTaskfile(node taskfile.Node) error {
 	if e.Taskfile, err = graph.Merge(); err != nil {
 		return err
 	}
+	if experiments.Plugins.Enabled() {
+		if e.plugins, err = reader.LoadPlugin(ctx, e.Taskfile); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -198,7 +199,17 @@ func (e *Executor) setupOutput() error {
 
 	var err error
 	e.Output, err = output.BuildFor(&e.OutputStyle, e.Logger)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Used for tasks fanned out to several SSH hosts
+	if prefixed, ok := e.Output.(*output.Prefixed); ok {
+		e.prefixedOutput = prefixed
+	} else {
+		e.prefixedOutput = output.NewPrefixed(e.Logger)
+	}
+	return nil
 }
 
 func (e *Executor) setupCompiler() error {
@@ -217,6 +228,9 @@ func (e *Executor) setupCompiler() error {
 		TaskfileEnv:    e.Taskfile.Env,
 		TaskfileVars:   e.Taskfile.Vars,
 		Logger:         e.Logger,
+		Plugins:        e.plugins,
+		InterpOptions:  e.Taskfile.InterpOptions,
+		RootTaskfile:   e.Taskfile.Location,
 	}
 	return nil
 }
@@ -27,6 +27,15 @@ func (e *Executor) InterceptInterruptSignals() {
 			}
 
 			e.Logger.Outf(logger.Yellow, "task: Signal received: %q\n", sig)
+
+			// Remote commands don't receive the signal from the terminal, so
+			// signal them first, then tear down the SSH connections if they
+			// still don't stop.
+			if i == 0 {
+				e.interruptSshCommands()
+			} else {
+				e.closeSshClients()
+			}
 		}
 	}()
 }
@@ -0,0 +1,390 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/env"
+	"github.com/go-task/task/v3/internal/filepathext"
+	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/internal/logger"
+	taskSsh "github.com/go-task/task/v3/internal/ssh"
+	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/internal/term"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// sshClient returns a pooled SSH client for the given compiled ssh config.
+// Clients are shared by every task of the current run targeting the same host.
+func (e *Executor) sshClient(ssh *ast.Ssh) (*taskSsh.SshClient, error) {
+	return e.sshPool.Get(e.sshOptions(ssh))
+}
+
+func (e *Executor) sshOptions(ssh *ast.Ssh) *taskSsh.NewOptions {
+	options := &taskSsh.NewOptions{
+		Addr:       ssh.Addr,
+		User:       ssh.User,
+		Password:   ssh.Password,
+		Key:        ssh.Key,
+		KeyPath:    ssh.KeyPath,
+		KnownHosts: ssh.KnownHosts,
+		Timeout:    ssh.Timeout,
+		Insecure:   ssh.Insecure || e.Insecure,
+	}
+	if ssh.Tofu {
+		options.TrustedHostsFile = filepath.Join(e.TempDir.Remote, "ssh", "known_hosts")
+		options.HostKeyPrompt = func(prompt string) error {
+			return e.Logger.Prompt(logger.Yellow, prompt, "n", "y", "yes")
+		}
+	}
+	for _, jump := range ssh.Jump {
+		options.Jump = append(options.Jump, e.sshOptions(jump))
+	}
+	return options
+}
+
+// sshConnectError wraps the error of connecting a task to its host. Host key
+// errors are returned as is, so that their own message and exit code stand out.
+func sshConnectError(taskName string, err error) error {
+	var notTrustedErr *errors.TaskSSHHostNotTrustedError
+	var keyChangedErr *errors.TaskSSHHostKeyChangedError
+	switch {
+	case errors.As(err, &notTrustedErr):
+		return notTrustedErr
+	case errors.As(err, &keyChangedErr):
+		return keyChangedErr
+	default:
+		return &errors.TaskSSHConnectError{TaskName: taskName, Err: err}
+	}
+}
+
+// setupSshForwards opens the forwards of the task and returns a copy of the
+// call exposing their addresses, to compile the task again with. For a
+// forward named DB, SSH_FORWARD_DB is the address it listens on and
+// SSH_FORWARD_DB_PORT its port; unnamed forwards use their index instead.
+// The forwards are closed by the returned func once the task is done.
+func (e *Executor) setupSshForwards(t *ast.Task, call *Call) (*Call, func(), error) {
+	client, err := e.sshClient(t.Ssh)
+	if err != nil {
+		return nil, nil, sshConnectError(call.Task, err)
+	}
+
+	var forwards []*taskSsh.Forward
+	closeForwards := func() {
+		for _, forward := range forwards {
+			if err := forward.Close(); err != nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: error closing ssh forward: %v\n", err)
+			}
+		}
+	}
+
+	forwardCall := *call
+	forwardCall.SshForwards = map[string]string{}
+	for i, f := range t.Ssh.Forwards {
+		forward, err := client.Forward(taskSsh.ForwardOptions{
+			Local:   f.Local,
+			Remote:  f.Remote,
+			Reverse: f.Reverse,
+		})
+		if err != nil {
+			closeForwards()
+			return nil, nil, &errors.TaskSSHConnectError{TaskName: call.Task, Err: err}
+		}
+		forwards = append(forwards, forward)
+
+		name := "SSH_FORWARD_" + strconv.Itoa(i)
+		if f.Name != "" {
+			name = "SSH_FORWARD_" + strings.ToUpper(f.Name)
+		}
+		forwardCall.SshForwards[name] = forward.Addr().String()
+		if addr, ok := forward.Addr().(*net.TCPAddr); ok {
+			forwardCall.SshForwards[name+"_PORT"] = strconv.Itoa(addr.Port)
+		}
+	}
+	return &forwardCall, closeForwards, nil
+}
+
+// compileForwardCmds templates the commands of the task again, with the
+// addresses of the forwards the call exposes. The rest of the task is kept as
+// compiled, so that its variables aren't evaluated again.
+func (e *Executor) compileForwardCmds(t *ast.Task, call *Call) error {
+	origTask, err := e.GetTask(call)
+	if err != nil {
+		return err
+	}
+	vars := t.Vars.DeepCopy()
+	for name, addr := range call.SshForwards {
+		vars.Set(name, ast.Var{Value: addr})
+	}
+	cache := &templater.Cache{Vars: vars}
+	cmds, err := compileCmds(origTask, t, vars, cache)
+	if err != nil {
+		return err
+	}
+	if cache.Err() != nil {
+		return cache.Err()
+	}
+	t.Vars = vars
+	t.Cmds = cmds
+	return nil
+}
+
+// sshInterruptKey is the context key of the context which is done once the
+// run is interrupted.
+type sshInterruptKey struct{}
+
+// withSshInterrupt returns the context of a run, whose remote commands are
+// signalled once Task is interrupted, and a func to call once the run is done.
+// Deferred commands run with a context of their own, so that they still run
+// after an interrupt.
+func (e *Executor) withSshInterrupt(ctx context.Context) (context.Context, context.CancelFunc) {
+	interrupt, cancel := context.WithCancel(context.Background())
+	e.sshInterruptMutex.Lock()
+	e.interruptSsh = cancel
+	e.sshInterruptMutex.Unlock()
+	return context.WithValue(ctx, sshInterruptKey{}, interrupt), cancel
+}
+
+// interruptSshCommands signals the remote commands of the current run.
+func (e *Executor) interruptSshCommands() {
+	e.sshInterruptMutex.Lock()
+	defer e.sshInterruptMutex.Unlock()
+	if e.interruptSsh != nil {
+		e.interruptSsh()
+	}
+}
+
+// closeSshClients closes all the SSH connections opened during the run.
+func (e *Executor) closeSshClients() {
+	if err := e.sshPool.Close(); err != nil {
+		e.Logger.VerboseErrf(logger.Yellow, "task: error closing ssh connections: %v\n", err)
+	}
+}
+
+// setupSshClient connects the task to its SSH host and uploads its files. Tasks
+// called with this_ssh reuse the client of the calling task instead.
+func (e *Executor) setupSshClient(t *ast.Task, call *Call) error {
+	if call.Indirect && call.SshClient != nil && t.Ssh == nil {
+		t.SshClient = call.SshClient
+		return nil
+	}
+	if t.Ssh == nil {
+		return nil
+	}
+
+	var err error
+	t.SshClient, err = e.sshClient(t.Ssh)
+	if err != nil {
+		return sshConnectError(call.Task, err)
+	}
+	if len(t.Ssh.Uploads) > 0 && !e.Dry {
+		uploads, err := sshUploads(t)
+		if err != nil {
+			return err
+		}
+		if err := t.SshClient.Upload(uploads); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sshUploads resolves the local sources of the uploads of the task. Files
+// matched by a glob are uploaded into the target, relative to the directory
+// the pattern starts in. Patterns matching no file are an error, as the
+// commands likely rely on them.
+func sshUploads(t *ast.Task) (taskSsh.UploadOptions, error) {
+	uploads := taskSsh.UploadOptions{}
+	for _, upload := range t.Ssh.Uploads {
+		source := filepathext.SmartJoin(t.Dir, upload.Source)
+		if !taskSsh.IsGlob(filepath.ToSlash(source)) {
+			upload.Source = source
+			uploads = append(uploads, upload)
+			continue
+		}
+
+		base := filepath.FromSlash(taskSsh.GlobBase(filepath.ToSlash(source)))
+		matches, err := fingerprint.Globs(t.Dir, []*ast.Glob{{Glob: upload.Source}})
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("ssh: no files match the upload %q", upload.Source)
+		}
+		for _, match := range matches {
+			rel, err := filepath.Rel(base, match)
+			if err != nil {
+				continue
+			}
+			uploads = append(uploads, ast.SshUpload{Source: match, Target: path.Join(upload.Target, filepath.ToSlash(rel))})
+		}
+	}
+	return uploads, nil
+}
+
+// downloadSshFiles fetches the downloads of the task once its commands ran.
+func (e *Executor) downloadSshFiles(t *ast.Task) error {
+	if t.Ssh == nil || len(t.Ssh.Downloads) == 0 || t.SshClient == nil || e.Dry {
+		return nil
+	}
+	downloads := taskSsh.DownloadOptions{}
+	for _, download := range t.Ssh.Downloads {
+		download.Target = filepathext.SmartJoin(t.Dir, download.Target)
+		downloads = append(downloads, download)
+	}
+	return t.SshClient.Download(downloads)
+}
+
+// checkSshDownloads makes sure the hosts a task is fanned out to don't download
+// files to the same local target, as they would overwrite each other.
+func checkSshDownloads(ssh *ast.Ssh, dir string) error {
+	if !ssh.IsFanOut() {
+		return nil
+	}
+	hosts := map[string]int{}
+	for i, host := range ssh.Hosts {
+		for _, download := range host.Downloads {
+			target := filepathext.SmartJoin(dir, download.Target)
+			if other, ok := hosts[target]; ok && other != i {
+				return fmt.Errorf("ssh: hosts %q and %q download to the same target %q", ssh.Hosts[other].HostName(), host.HostName(), download.Target)
+			}
+			hosts[target] = i
+		}
+	}
+	return nil
+}
+
+// hostTask returns the copy of a fanned out task which runs on the given host.
+func hostTask(t *ast.Task, host *ast.Ssh) *ast.Task {
+	ht := t.DeepCopy()
+	ht.Ssh = host
+	ht.Label = fmt.Sprintf("%s@%s", t.Name(), host.HostName())
+	ht.Prefix = host.HostName()
+	return ht
+}
+
+type sshHostResult struct {
+	err     error
+	skipped bool
+}
+
+// runTaskOnHosts runs the task on every host it is fanned out to. Hosts run
+// concurrently, bounded by the concurrency limit, and their output is prefixed
+// with the host name. Unless the task is set to fail fast, a failing host
+// doesn't stop the others and all failures are reported at the end.
+func (e *Executor) runTaskOnHosts(ctx context.Context, t *ast.Task, call *Call) error {
+	reacquire := e.releaseConcurrencyLimit()
+	defer reacquire()
+
+	g := &errgroup.Group{}
+	if t.Ssh.FailFast {
+		g, ctx = errgroup.WithContext(ctx)
+	}
+
+	results := make([]sshHostResult, len(t.Ssh.Hosts))
+	for i, host := range t.Ssh.Hosts {
+		g.Go(func() error {
+			release := e.acquireConcurrencyLimit()
+			defer release()
+
+			if ctx.Err() != nil {
+				results[i].skipped = true
+				return nil
+			}
+
+			ht := hostTask(t, host)
+			hostCall := *call
+			hostCall.SshClient = nil
+			hostCall.Host = host.HostName()
+
+			results[i].err = e.executeTask(ctx, ht, &hostCall)
+			return results[i].err
+		})
+	}
+	_ = g.Wait()
+
+	printSummary := e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent)
+	var failedHosts []string
+	var errs []error
+	for i, host := range t.Ssh.Hosts {
+		name := host.HostName()
+		switch {
+		case results[i].skipped:
+			if printSummary {
+				e.Logger.Errf(logger.Yellow, "task: [%s] %s: skipped\n", t.Name(), name)
+			}
+		case results[i].err != nil:
+			failedHosts = append(failedHosts, name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, results[i].err))
+			if printSummary {
+				e.Logger.Errf(logger.Red, "task: [%s] %s: failed: %v\n", t.Name(), name, results[i].err)
+			}
+		default:
+			if printSummary {
+				e.Logger.Errf(logger.Green, "task: [%s] %s: ok\n", t.Name(), name)
+			}
+		}
+	}
+
+	if len(failedHosts) > 0 {
+		return &errors.TaskSSHHostsError{
+			TaskName:    t.Task,
+			FailedHosts: failedHosts,
+			Err:         errors.Join(errs...),
+		}
+	}
+	return nil
+}
+
+// runSshCommand runs a command on the host of the task. Interactive tasks get
+// a pseudo terminal sized like the local one, which stays in raw mode until
+// the command exits, so that keys like Ctrl-C reach the remote command.
+func (e *Executor) runSshCommand(ctx context.Context, t *ast.Task, command string, stdout, stderr io.Writer) error {
+	// Interrupting Task cancels the remote command too
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if interrupt, ok := ctx.Value(sshInterruptKey{}).(context.Context); ok {
+		defer context.AfterFunc(interrupt, cancel)()
+	}
+
+	options := &taskSsh.RunOptions{
+		Commands: []string{command},
+		Env:      env.GetMap(t, false),
+		Stdin:    e.Stdin,
+		Stdout:   stdout,
+		Stderr:   stderr,
+	}
+	if !t.Interactive || !term.IsTerminal() {
+		return t.SshClient.Run(ctx, options)
+	}
+
+	restore, err := term.MakeRaw()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = restore() }()
+
+	if e.Stdin == os.Stdin {
+		stdin, stop := term.Stdin()
+		defer stop()
+		options.Stdin = stdin
+	}
+	resize := make(chan struct{}, 1)
+	defer term.NotifyResize(resize)()
+	options.Tty = &taskSsh.TtyOptions{
+		Term:   os.Getenv("TERM"),
+		Size:   term.Size,
+		Resize: resize,
+	}
+	return t.SshClient.Run(ctx, options)
+}
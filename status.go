@@ -3,13 +3,17 @@ package task
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/go-task/task/v3/internal/fingerprint"
+	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 // Status returns an error if any the of given tasks is not up-to-date
 func (e *Executor) Status(ctx context.Context, calls ...*Call) error {
+	defer e.closeSshClients()
+
 	for _, call := range calls {
 
 		// Compile the task
@@ -18,24 +22,47 @@ func (e *Executor) Status(ctx context.Context, calls ...*Call) error {
 			return err
 		}
 
-		// Get the fingerprinting method to use
-		method := e.Taskfile.Method
-		if t.Method != "" {
-			method = t.Method
+		// Tasks running on SSH hosts are checked on each of them
+		tasks := []*ast.Task{t}
+		if t.Ssh.IsFanOut() {
+			tasks = tasks[:0]
+			for _, host := range t.Ssh.Hosts {
+				tasks = append(tasks, hostTask(t, host))
+			}
 		}
 
-		// Check if the task is up-to-date
-		isUpToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
-			fingerprint.WithMethod(method),
-			fingerprint.WithTempDir(e.TempDir.Fingerprint),
-			fingerprint.WithDry(e.Dry),
-			fingerprint.WithLogger(e.Logger),
-		)
-		if err != nil {
-			return err
-		}
-		if !isUpToDate {
-			return fmt.Errorf(`task: Task "%s" is not up-to-date`, t.Name())
+		for _, t := range tasks {
+			if t.Ssh != nil {
+				if t.SshClient, err = e.sshClient(t.Ssh); err != nil {
+					return sshConnectError(call.Task, err)
+				}
+			}
+
+			// Get the fingerprinting method to use
+			method := e.Taskfile.Method
+			if t.Method != "" {
+				method = t.Method
+			}
+
+			checker, err := e.sourcesChecker(t, method)
+			if err != nil {
+				return err
+			}
+
+			// Check if the task is up-to-date
+			isUpToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
+				fingerprint.WithMethod(method),
+				fingerprint.WithSourcesChecker(checker),
+				fingerprint.WithTempDir(e.TempDir.Fingerprint),
+				fingerprint.WithDry(e.Dry),
+				fingerprint.WithLogger(e.Logger),
+			)
+			if err != nil {
+				return err
+			}
+			if !isUpToDate {
+				return fmt.Errorf(`task: Task "%s" is not up-to-date`, t.Name())
+			}
 		}
 	}
 	return nil
@@ -46,9 +73,29 @@ func (e *Executor) statusOnError(t *ast.Task) error {
 	if method == "" {
 		method = e.Taskfile.Method
 	}
-	checker, err := fingerprint.NewSourcesChecker(method, e.TempDir.Fingerprint, e.Dry)
+	checker, err := e.sourcesChecker(t, method)
 	if err != nil {
 		return err
 	}
 	return checker.OnError(t)
 }
+
+// sourcesChecker returns the checker of the sources of the task for the given
+// method. Methods written as <plugin>.<export> are implemented by plugins.
+func (e *Executor) sourcesChecker(t *ast.Task, method string) (fingerprint.SourcesCheckable, error) {
+	pluginName, export, isPlugin := strings.Cut(method, ".")
+	switch {
+	case isPlugin && !e.plugins.Has(pluginName, export):
+		return nil, fmt.Errorf(`task: invalid method "%s": plugin %q has no export %q`, method, pluginName, export)
+	case isPlugin && t.SshClient != nil:
+		return nil, fmt.Errorf(`task: method "%s" is not supported by tasks running on an SSH host`, method)
+	case isPlugin:
+		return fingerprint.NewPluginChecker(method, e.Dry, func(input []byte) ([]byte, error) {
+			return e.plugins.Call(taskfile.WithPluginOutput(context.Background(), e.Stdout, e.Stderr), pluginName, export, nil, input)
+		}), nil
+	case t.SshClient != nil:
+		return fingerprint.NewRemoteSourcesChecker(method, e.TempDir.Fingerprint, e.Dry)
+	default:
+		return fingerprint.NewSourcesChecker(method, e.TempDir.Fingerprint, e.Dry)
+	}
+}
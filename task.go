@@ -15,14 +15,12 @@ import (
 	"github.com/go-task/task/v3/experiments"
 	"github.com/go-task/task/v3/internal/env"
 	"github.com/go-task/task/v3/internal/execext"
-	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	taskJs "github.com/go-task/task/v3/internal/js"
 	"github.com/go-task/task/v3/internal/logger"
 	"github.com/go-task/task/v3/internal/output"
 	"github.com/go-task/task/v3/internal/slicesext"
 	"github.com/go-task/task/v3/internal/sort"
-	taskSsh "github.com/go-task/task/v3/internal/ssh"
 	"github.com/go-task/task/v3/internal/summary"
 	"github.com/go-task/task/v3/internal/templater"
 	"github.com/go-task/task/v3/taskfile/ast"
@@ -82,6 +80,10 @@ func (e *Executor) Run(ctx context.Context, calls ...*Call) error {
 		return err
 	}
 
+	defer e.closeSshClients()
+	ctx, stopInterrupt := e.withSshInterrupt(ctx)
+	defer stopInterrupt()
+
 	g, ctx := errgroup.WithContext(ctx)
 	for _, c := range regularCalls {
 		c := c
@@ -122,7 +124,7 @@ func (e *Executor) splitRegularAndWatchCalls(calls ...*Call) (regularCalls []*Ca
 
 // RunTask runs a task by its name
 func (e *Executor) RunTask(ctx context.Context, call *Call) error {
-	t, err := e.FastCompiledTask(call)
+	t, err := e.compiledTask(ctx, call, false)
 	if err != nil {
 		return err
 	}
@@ -142,7 +144,7 @@ func (e *Executor) RunTask(ctx context.Context, call *Call) error {
 		return err
 	}
 
-	t, err = e.CompiledTask(call)
+	t, err = e.compiledTask(ctx, call, true)
 	if err != nil {
 		return err
 	}
@@ -162,122 +164,156 @@ func (e *Executor) RunTask(ctx context.Context, call *Call) error {
 	defer release()
 
 	return e.startExecution(ctx, t, func(ctx context.Context) error {
-		e.Logger.VerboseErrf(logger.Magenta, "task: %q started\n", call.Task)
-		if err := e.runDeps(ctx, t); err != nil {
-			return err
-		}
-
-		skipFingerprinting := e.ForceAll || (!call.Indirect && e.Force)
-		if !skipFingerprinting {
-			if err := ctx.Err(); err != nil {
+		return e.runWithPluginHooks(ctx, t, func(ctx context.Context) error {
+			e.Logger.VerboseErrf(logger.Magenta, "task: %q started\n", call.Task)
+			if err := e.runDeps(ctx, t); err != nil {
 				return err
 			}
 
-			if call.Indirect && call.SshClient != nil && t.Ssh == nil {
-				t.SshClient = call.SshClient
-			} else if t.Ssh != nil {
-				t.SshClient, err = taskSsh.NewSshClient(&taskSsh.NewOptions{
-					Addr:       t.Ssh.Addr,
-					User:       t.Ssh.User,
-					Password:   t.Ssh.Password,
-					Key:        t.Ssh.Key,
-					KeyPath:    t.Ssh.KeyPath,
-					KnownHosts: t.Ssh.KnownHosts,
-					Timeout:    t.Ssh.Timeout,
-					Insecure:   t.Ssh.Insecure || e.Insecure,
-				})
-				if err != nil {
-					return &errors.TaskSSHConnectError{TaskName: call.Task, Err: err}
+			if t.Ssh.IsFanOut() {
+				if err := e.promptTask(t, call); err != nil {
+					return err
 				}
-				defer t.SshClient.Close()
-				if len(t.Ssh.Uploads) > 0 {
-					u := taskSsh.UploadOnceOptions{}
-					for _, upload := range t.Ssh.Uploads {
-						upload.Source = filepathext.SmartJoin(t.Dir, upload.Source)
-						u = append(u, upload)
-					}
-					if err := t.SshClient.UploadOnce(u); err != nil {
+				if err := e.runTaskOnHosts(ctx, t, call); err != nil {
+					if call.Indirect {
 						return err
 					}
+					return &errors.TaskRunError{TaskName: t.Task, Err: err}
 				}
+				e.Logger.VerboseErrf(logger.Magenta, "task: %q finished\n", call.Task)
+				return nil
 			}
 
-			preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
-			if err != nil {
-				return err
-			}
+			return e.executeTask(ctx, t, call)
+		})
+	})
+}
 
-			// Get the fingerprinting method to use
-			method := e.Taskfile.Method
-			if t.Method != "" {
-				method = t.Method
-			}
-			upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
-				fingerprint.WithMethod(method),
-				fingerprint.WithTempDir(e.TempDir.Fingerprint),
-				fingerprint.WithDry(e.Dry),
-				fingerprint.WithLogger(e.Logger),
-			)
-			if err != nil {
-				return err
-			}
+// executeTask connects to the SSH host if any, checks whether the task is up
+// to date and runs its commands.
+func (e *Executor) executeTask(ctx context.Context, t *ast.Task, call *Call) error {
+	if err := e.setupSshClient(t, call); err != nil {
+		return err
+	}
 
-			if upToDate && preCondMet {
-				if e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
-					e.Logger.Errf(logger.Magenta, "task: Task %q is up to date\n", t.Name())
-				}
-				return nil
-			}
+	skipFingerprinting := e.ForceAll || (!call.Indirect && e.Force)
+	if !skipFingerprinting {
+		if err := ctx.Err(); err != nil {
+			return err
 		}
 
-		for _, p := range t.Prompt {
-			if p != "" && !e.Dry {
-				if err := e.Logger.Prompt(logger.Yellow, p, "n", "y", "yes"); errors.Is(err, logger.ErrNoTerminal) {
-					return &errors.TaskCancelledNoTerminalError{TaskName: call.Task}
-				} else if errors.Is(err, logger.ErrPromptCancelled) {
-					return &errors.TaskCancelledByUserError{TaskName: call.Task}
-				} else if err != nil {
-					return err
-				}
+		preCondMet, err := e.areTaskPreconditionsMet(ctx, t)
+		if err != nil {
+			return err
+		}
+
+		// Get the fingerprinting method to use
+		method := e.Taskfile.Method
+		if t.Method != "" {
+			method = t.Method
+		}
+		checker, err := e.sourcesChecker(t, method)
+		if err != nil {
+			return err
+		}
+		upToDate, err := fingerprint.IsTaskUpToDate(ctx, t,
+			fingerprint.WithMethod(method),
+			fingerprint.WithSourcesChecker(checker),
+			fingerprint.WithTempDir(e.TempDir.Fingerprint),
+			fingerprint.WithDry(e.Dry),
+			fingerprint.WithLogger(e.Logger),
+		)
+		if err != nil {
+			return err
+		}
+
+		if upToDate && preCondMet {
+			if e.Verbose || (!call.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
+				e.Logger.Errf(logger.Magenta, "task: Task %q is up to date\n", t.Name())
 			}
+			return nil
 		}
+	}
 
-		if err := e.mkdir(t); err != nil {
-			e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+	// Fanned out tasks are prompted once for all the hosts
+	if call.Host == "" {
+		if err := e.promptTask(t, call); err != nil {
+			return err
 		}
+	}
 
-		var deferredExitCode uint8
+	// Forwards are only opened once the task is known to run, and their
+	// addresses are made available to its commands by templating them again
+	if t.Ssh != nil && len(t.Ssh.Forwards) > 0 && !e.Dry {
+		forwardCall, closeForwards, err := e.setupSshForwards(t, call)
+		if err != nil {
+			return err
+		}
+		defer closeForwards()
+		call = forwardCall
+		if err := e.compileForwardCmds(t, call); err != nil {
+			return err
+		}
+	}
 
-		for i := range t.Cmds {
-			if t.Cmds[i].Defer {
-				defer e.runDeferred(t, call, i, &deferredExitCode)
-				continue
-			}
+	if err := e.mkdir(t); err != nil {
+		e.Logger.Errf(logger.Red, "task: cannot make directory %q: %v\n", t.Dir, err)
+	}
 
-			if err := e.runCommand(ctx, t, call, i); err != nil {
-				if err2 := e.statusOnError(t); err2 != nil {
-					e.Logger.VerboseErrf(logger.Yellow, "task: error cleaning status on error: %v\n", err2)
-				}
+	var deferredExitCode uint8
 
-				var exitCode interp.ExitStatus
-				if errors.As(err, &exitCode) {
-					if t.IgnoreError {
-						e.Logger.VerboseErrf(logger.Yellow, "task: task error ignored: %v\n", err)
-						continue
-					}
-					deferredExitCode = uint8(exitCode)
-				}
+	for i := range t.Cmds {
+		if t.Cmds[i].Defer {
+			defer e.runDeferred(t, call, i, &deferredExitCode)
+			continue
+		}
 
-				if call.Indirect {
-					return err
+		if err := e.runCommand(ctx, t, call, i); err != nil {
+			if err2 := e.statusOnError(t); err2 != nil {
+				e.Logger.VerboseErrf(logger.Yellow, "task: error cleaning status on error: %v\n", err2)
+			}
+
+			var exitCode interp.ExitStatus
+			if errors.As(err, &exitCode) {
+				if t.IgnoreError {
+					e.Logger.VerboseErrf(logger.Yellow, "task: task error ignored: %v\n", err)
+					continue
 				}
+				deferredExitCode = uint8(exitCode)
+			}
 
-				return &errors.TaskRunError{TaskName: t.Task, Err: err}
+			if call.Indirect || call.Host != "" {
+				return err
 			}
+
+			return &errors.TaskRunError{TaskName: t.Task, Err: err}
+		}
+	}
+	if err := e.downloadSshFiles(t); err != nil {
+		if call.Indirect || call.Host != "" {
+			return err
 		}
+		return &errors.TaskRunError{TaskName: t.Task, Err: err}
+	}
+	if call.Host == "" {
 		e.Logger.VerboseErrf(logger.Magenta, "task: %q finished\n", call.Task)
-		return nil
-	})
+	}
+	return nil
+}
+
+func (e *Executor) promptTask(t *ast.Task, call *Call) error {
+	for _, p := range t.Prompt {
+		if p != "" && !e.Dry {
+			if err := e.Logger.Prompt(logger.Yellow, p, "n", "y", "yes"); errors.Is(err, logger.ErrNoTerminal) {
+				return &errors.TaskCancelledNoTerminalError{TaskName: call.Task}
+			} else if errors.Is(err, logger.ErrPromptCancelled) {
+				return &errors.TaskCancelledByUserError{TaskName: call.Task}
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 func (e *Executor) mkdir(t *ast.Task) error {
@@ -338,12 +374,26 @@ func (e *Executor) runDeferred(t *ast.Task, call *Call, i int, deferredExitCode
 	cmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
 	cmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, extra)
 	cmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, extra)
+	cmd.Plugin = templater.ReplaceWithExtra(cmd.Plugin, cache, extra)
+	cmd.Input = templater.ReplaceWithExtra(cmd.Input, cache, extra)
 
 	if err := e.runCommand(ctx, t, call, i); err != nil {
 		e.Logger.VerboseErrf(logger.Yellow, "task: ignored error in deferred cmd: %s\n", err.Error())
 	}
 }
 
+// taskOutput returns the output the commands of the task write to.
+func (e *Executor) taskOutput(t *ast.Task, call *Call) output.Output {
+	switch {
+	case t.Interactive:
+		return output.Interleaved{}
+	case call.Host != "":
+		return e.prefixedOutput
+	default:
+		return e.Output
+	}
+}
+
 func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *Call, i int) error {
 	cmd := t.Cmds[i]
 
@@ -361,9 +411,13 @@ func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *Call, i in
 			return err
 		}
 		return nil
-	case cmd.Cmd != "":
+	case cmd.Cmd != "", cmd.Plugin != "":
+		command := cmd.Cmd
+		if cmd.Plugin != "" {
+			command = "plugin: " + cmd.Plugin
+		}
 		if !shouldRunOnCurrentPlatform(cmd.Platforms) {
-			e.Logger.VerboseOutf(logger.Yellow, "task: [%s] %s not for current platform - ignored\n", t.Name(), cmd.Cmd)
+			e.Logger.VerboseOutf(logger.Yellow, "task: [%s] %s not for current platform - ignored\n", t.Name(), command)
 			return nil
 		}
 
@@ -375,53 +429,47 @@ func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *Call, i in
 		}
 
 		if e.Verbose || (!call.Silent && !cmd.Silent && !t.Silent && !e.Taskfile.Silent && !e.Silent) {
-			e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), cmd.Cmd)
+			e.Logger.Errf(logger.Green, "task: [%s] %s\n", t.Name(), command)
 		}
 
 		if e.Dry {
 			return nil
 		}
 
-		outputWrapper := e.Output
-		if t.Interactive {
-			outputWrapper = output.Interleaved{}
-		}
 		vars, err := e.Compiler.FastGetVariables(t, call)
 		outputTemplater := &templater.Cache{Vars: vars}
 		if err != nil {
 			return fmt.Errorf("task: failed to get variables: %w", err)
 		}
-		stdOut, stdErr, closer := outputWrapper.WrapWriter(e.Stdout, e.Stderr, t.Prefix, outputTemplater)
+		stdOut, stdErr, closer := e.taskOutput(t, call).WrapWriter(e.Stdout, e.Stderr, t.Prefix, outputTemplater)
 
-		if t.SshClient != nil {
-			err = t.SshClient.Run(&taskSsh.RunOptions{
-				Commands: []string{cmd.Cmd},
-				Env:      env.GetMap(t, false),
-				Stdin:    e.Stdin,
-				Stdout:   stdOut,
-				Stderr:   stdErr,
-			})
+		if cmd.Plugin != "" {
+			err = e.runPluginCommand(ctx, t, cmd, vars, stdOut, stdErr)
+		} else if t.SshClient != nil {
+			err = e.runSshCommand(ctx, t, cmd.Cmd, stdOut, stdErr)
 		} else {
 			intp := "sh"
 			if experiments.Interp.Enabled() {
 				intp = cmd.Interp
 			}
+			sandbox := e.Compiler.jsSandbox(t, t.Dir)
 			switch intp {
 			case "javascript", "js", "civet":
-				taskJs.Setup()
-				if js, jsErr := taskJs.NewJavaScript(); jsErr == nil {
-					defer js.Close()
+				if js, jsErr := taskJs.Get(sandbox); jsErr == nil {
+					defer js.Release()
 					_, err = js.Eval(&taskJs.JSEvalOptions{
 						Script:  cmd.Cmd,
 						Dialect: intp,
 						Dir:     t.Dir,
 						Env:     env.GetMap(t, true),
+						Vars:    vars.ToCacheMap(),
+						Host:    &jsHost{e: e, ctx: ctx, t: t, sandbox: sandbox, stderr: stdErr},
 						Stdin:   e.Stdin,
 						Stdout:  stdOut,
 						Stderr:  stdErr,
 					})
 				} else {
-					err = fmt.Errorf("js: uninitialized")
+					err = fmt.Errorf("js: uninitialized: %w", jsErr)
 				}
 			default:
 				err = execext.RunCommand(ctx, &execext.RunCommandOptions{
@@ -433,6 +481,7 @@ func (e *Executor) runCommand(ctx context.Context, t *ast.Task, call *Call, i in
 					Stdin:     e.Stdin,
 					Stdout:    stdOut,
 					Stderr:    stdErr,
+					JSSandbox: sandbox,
 				})
 			}
 		}
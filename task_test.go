@@ -2,6 +2,8 @@ package task_test
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/fs"
@@ -11,6 +13,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"os/user"
 	"path/filepath"
 	"regexp"
@@ -1403,6 +1406,394 @@ func TestPlugins(t *testing.T) {
 	})
 }
 
+var testPlugins sync.Map
+
+// buildTestPlugin compiles the Go plugin of the directory to wasm, once for
+// all the tests using it.
+func buildTestPlugin(t *testing.T, dir, name string) {
+	t.Helper()
+
+	build, _ := testPlugins.LoadOrStore(dir, sync.OnceValue(func() error {
+		cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", name, ".")
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%w: %s", err, out)
+		}
+		return nil
+	}))
+	require.NoError(t, build.(func() error)())
+}
+
+func TestPluginHostFunctions(t *testing.T) { // nolint:paralleltest // experiment must stay enabled for the subtests
+	enableExperimentForTest(t, &experiments.Plugins, 1)
+
+	const dir = "testdata/plugins/host"
+	buildTestPlugin(t, dir, "host.wasm")
+
+	tests := []struct {
+		task        string
+		expected    []string
+		expectedErr string
+	}{
+		{task: "greet", expected: []string{"Hello world from Task\n"}},
+		{task: "greet-var", expected: []string{"Hello var from Task\n"}},
+		{task: "greet-vars", expected: []string{"Hello one from Task\n", "Hello two from Task\n"}},
+		{task: "env", expected: []string{"hi <unset>\n"}},
+		{task: "env-task", expected: []string{"echo 'hello'\nhello\n", "plugin: host.env\nhello\n"}},
+		{task: "env-sandboxed", expectedErr: `task: Plugin "sandboxed" is not allowed to read the environment, set env: true to allow it`},
+		{task: "log", expected: []string{"from plugin\nfrom plugin\nfrom plugin\nfrom plugin\n"}},
+		{task: "run", expected: []string{"called from plugin\n", "ok\n"}},
+		{task: "run-var", expected: []string{"called from plugin dynamic\n", "ran ok\n"}},
+		{task: "args", expected: []string{"one\n", `["one",2,["three",true]]` + "\n", `{"four":4}` + "\n"}},
+		{task: "fail", expectedErr: `task: Plugin "host" failed to call "fail": something went wrong`},
+		{task: "run-sandboxed", expected: []string{`task: Plugin "sandboxed" is not allowed to run tasks`}},
+		{task: "host", expected: []string{"default from plugin\n"}},
+		{task: "host:hello", expected: []string{"Hello plugin from Task\n"}},
+		{task: "sandboxed:hello", expected: []string{"Hello plugin from Task\n"}},
+		{task: "fresh", expected: []string{`task: Task "fresh" is up to date`}},
+		{task: "stale", expected: []string{"stale 1 sources\n"}},
+		{task: "invalid-method", expectedErr: `task: invalid method "host.missing"`},
+		{task: "plugin-cmds", expected: []string{
+			"task: [plugin-cmds] plugin: host.greet\nHello world from Task\n",
+			`{"input":"{\"name\":\"Task\"}"}` + "\n",
+			`{"input":"a"}` + "\n",
+			`{"input":"b"}` + "\n",
+			`task: "plugin-cmds" not meet if - skipped`,
+			`command error ignored: task: Plugin "host" failed to call "exit": exiting with 3` + "\ntask: [plugin-cmds] echo done\ndone\n",
+			`{"input":"deferred"}` + "\n",
+		}},
+		{task: "plugin-cmd-exit", expectedErr: `task: Plugin "host" failed to call "exit": exiting with 3`},
+		{task: "allocate", expected: []string{"8 MiB\n"}},
+		{task: "exhaust-memory", expectedErr: `task: Plugin "limited" failed to call "allocate", it exceeded its max_memory of 64 MiB`},
+		{task: "spin", expectedErr: `task: Plugin "limited" exceeded its timeout of 1s calling "spin"`},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
+
+			var buff bytes.Buffer
+			e := task.NewExecutor(
+				task.WithDir(dir),
+				task.WithStdout(&buff),
+				task.WithStderr(&buff),
+				task.WithSilent(true),
+				task.WithVerbose(true),
+			)
+			require.NoError(t, e.Setup())
+			err := e.Run(t.Context(), &task.Call{Task: test.task})
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			for _, expected := range test.expected {
+				assert.Contains(t, buff.String(), expected)
+			}
+		})
+	}
+}
+
+func TestPluginOutput(t *testing.T) { // nolint:paralleltest // experiment must stay enabled for the subtests
+	enableExperimentForTest(t, &experiments.Plugins, 1)
+
+	const dir = "testdata/plugins/host"
+	buildTestPlugin(t, dir, "host.wasm")
+
+	t.Run("prefixed", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.NewExecutor(
+			task.WithDir(dir),
+			task.WithStdout(&buff),
+			task.WithStderr(&buff),
+			task.WithSilent(true),
+			task.WithOutputStyle(ast.Output{Name: "prefixed"}),
+		)
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(t.Context(), &task.Call{Task: "print"}))
+
+		lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+		assert.ElementsMatch(t, []string{
+			"[print] stdout cmd",
+			"[print] stderr cmd",
+			"[print] log cmd",
+			"[print] output cmd",
+			"[print] log sandboxed",
+			"[print] output sandboxed",
+		}, lines)
+	})
+
+	t.Run("prefixed template func", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.NewExecutor(
+			task.WithDir(dir),
+			task.WithStdout(&buff),
+			task.WithStderr(&buff),
+			task.WithSilent(true),
+			task.WithOutputStyle(ast.Output{Name: "prefixed"}),
+		)
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(t.Context(), &task.Call{Task: "print-func"}))
+
+		// The functions write to the output of the task calling them
+		for _, line := range []string{"stdout func", "stderr func", "log func", "output func"} {
+			assert.Contains(t, buff.String(), "[print-func] "+line+"\n")
+		}
+	})
+
+	t.Run("capture", func(t *testing.T) {
+		t.Parallel()
+
+		var buff bytes.Buffer
+		e := task.NewExecutor(
+			task.WithDir(dir),
+			task.WithStdout(&buff),
+			task.WithStderr(&buff),
+			task.WithSilent(true),
+		)
+		require.NoError(t, e.Setup())
+		require.NoError(t, e.Run(t.Context(), &task.Call{Task: "capture"}))
+		assert.Contains(t, buff.String(), "stderr var\nlog var\n")
+		assert.Contains(t, buff.String(), "captured stdout var\noutput var\n")
+		assert.Contains(t, buff.String(), "with stdout logs\nstderr logs\nlog logs\noutput logs\n")
+	})
+}
+
+func TestListPlugins(t *testing.T) { // nolint:paralleltest // experiment must stay enabled for the test
+	enableExperimentForTest(t, &experiments.Plugins, 1)
+
+	const dir = "testdata/plugins/host"
+	buildTestPlugin(t, dir, "host.wasm")
+
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithColor(false),
+	)
+	require.NoError(t, e.Setup())
+	require.NoError(t, e.ListPlugins())
+
+	lines := strings.Split(strings.TrimSpace(buff.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, "task: Plugins of this project:", lines[0])
+	assert.Regexp(t, `^\* host: +testdata/plugins/host/host\.wasm +env, run_tasks$`, lines[1])
+	assert.Regexp(t, `^\* sandboxed: +testdata/plugins/host/host\.wasm +no capabilities$`, lines[2])
+	assert.Regexp(t, `^\* printer: +testdata/plugins/host/host\.wasm +stderr, stdout$`, lines[3])
+	assert.Regexp(t, `^\* limited: +testdata/plugins/host/host\.wasm +no capabilities +\(max_memory: 64 MiB, timeout: 1s\)$`, lines[4])
+}
+
+func TestPluginHooks(t *testing.T) { // nolint:paralleltest // experiment must stay enabled for the subtests
+	enableExperimentForTest(t, &experiments.Plugins, 1)
+
+	const dir = "testdata/plugins/hooks"
+	buildTestPlugin(t, "testdata/plugins/host", "host.wasm")
+
+	tests := []struct {
+		name           string
+		entrypoint     string
+		task           string
+		expected       []string
+		notExpected    string
+		expectedErr    string
+		notExpectedErr string
+	}{
+		{
+			name:     "ok",
+			task:     "ok",
+			expected: []string{"before_task ok 0\nok\nafter_task ok 0\n"},
+		},
+		{
+			name:        "fail",
+			task:        "fail",
+			expected:    []string{"before_task fail 0\n", "on_error fail 3\nafter_task fail 3\n"},
+			expectedErr: `task: Failed to run task "fail"`,
+		},
+		{
+			name:        "fatal",
+			task:        "forbidden",
+			notExpected: "forbidden ran",
+			expectedErr: "task names must not be forbidden",
+		},
+		{
+			name:       "warn",
+			entrypoint: "Taskfile.warn.yml",
+			task:       "forbidden",
+			expected:   []string{"task names must not be forbidden", "forbidden ran\n"},
+		},
+		{
+			name:        "on_error fatal",
+			task:        "fail-hook",
+			expected:    []string{"after_task fail-hook 3\n"},
+			expectedErr: "on_error hook failed",
+		},
+		{
+			name:           "on_error warn",
+			entrypoint:     "Taskfile.warn.yml",
+			task:           "fail-hook",
+			expected:       []string{"on_error hook failed"},
+			expectedErr:    `task: Failed to run task "fail-hook"`,
+			notExpectedErr: "on_error hook failed",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buff SyncBuffer
+			opts := []task.ExecutorOption{
+				task.WithDir(dir),
+				task.WithStdout(&buff),
+				task.WithStderr(&buff),
+				task.WithSilent(true),
+			}
+			if test.entrypoint != "" {
+				opts = append(opts, task.WithEntrypoint(filepath.Join(dir, test.entrypoint)))
+			}
+			e := task.NewExecutor(opts...)
+			require.NoError(t, e.Setup())
+			err := e.Run(t.Context(), &task.Call{Task: test.task})
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			if test.notExpectedErr != "" {
+				assert.NotContains(t, err.Error(), test.notExpectedErr)
+			}
+			for _, expected := range test.expected {
+				assert.Contains(t, buff.buf.String(), expected)
+			}
+			if test.notExpected != "" {
+				assert.NotContains(t, buff.buf.String(), test.notExpected)
+			}
+		})
+	}
+}
+
+func TestPluginIncludes(t *testing.T) { // nolint:paralleltest // experiments must stay enabled for the subtests
+	enableExperimentForTest(t, &experiments.Plugins, 1)
+	enableExperimentForTest(t, &experiments.RemoteTaskfiles, 1)
+
+	buildTestPlugin(t, "testdata/plugins/host", "host.wasm")
+	wasm, err := os.ReadFile("testdata/plugins/host/host.wasm")
+	require.NoError(t, err)
+	checksum := fmt.Sprintf("%x", sha256.Sum256(wasm))
+
+	remoteDir := t.TempDir()
+	srv := httptest.NewServer(http.FileServer(http.Dir(remoteDir)))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(dir, "Taskfile.yml"): fmt.Sprintf(`version: "3"
+
+includes:
+  lib:
+    taskfile: ./lib
+    dir: ./lib
+  remote: %s/Taskfile.yml
+`, srv.URL),
+		filepath.Join(dir, "Taskfile.checksum.yml"): `version: "3"
+
+plugins:
+  host:
+    file: lib/host.wasm
+    checksum: 0123456789abcdef
+
+tasks:
+  default: echo "should not run"
+`,
+		filepath.Join(dir, "lib", "Taskfile.yml"): fmt.Sprintf(`version: "3"
+
+plugins:
+  host:
+    file: host.wasm
+    checksum: %s
+    env: true
+
+vars:
+  NAME: lib
+
+tasks:
+  greet:
+    vars:
+      WHO: world
+    cmd: echo '{{host_greet "WHO"}} {{lib_host_env "TASK_PLUGIN_UNSET"}}'
+
+  fresh:
+    method: host.fingerprint
+    sources:
+      - fresh.up-to-date
+    cmd: echo "should not run"
+`, checksum),
+		filepath.Join(dir, "lib", "fresh.up-to-date"): "",
+		filepath.Join(remoteDir, "Taskfile.yml"): fmt.Sprintf(`version: "3"
+
+plugins:
+  host:
+    file: host.wasm
+    checksum: %s
+`, checksum),
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+	for _, name := range []string{filepath.Join(dir, "lib", "host.wasm"), filepath.Join(remoteDir, "host.wasm")} {
+		require.NoError(t, os.WriteFile(name, wasm, 0o644))
+	}
+
+	tests := []struct {
+		task     string
+		expected string
+	}{
+		{task: "lib:greet", expected: "Hello world from lib <unset>\n"},
+		{task: "lib:fresh", expected: `task: Task "lib:fresh" is up to date`},
+		{task: "lib:host:hello", expected: "Hello plugin from lib\n"},
+		{task: "remote:host", expected: "default from plugin\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.task, func(t *testing.T) {
+			t.Parallel()
+
+			var buff SyncBuffer
+			e := task.NewExecutor(
+				task.WithDir(dir),
+				task.WithStdout(&buff),
+				task.WithStderr(&buff),
+				task.WithTimeout(time.Minute),
+				task.WithInsecure(true),
+				task.WithAssumeYes(true),
+				task.WithSilent(true),
+				task.WithVerbose(true),
+			)
+			require.NoError(t, e.Setup())
+			require.NoError(t, e.Run(t.Context(), &task.Call{Task: test.task}))
+			assert.Contains(t, buff.buf.String(), test.expected)
+		})
+	}
+
+	t.Run("checksum", func(t *testing.T) {
+		t.Parallel()
+
+		e := task.NewExecutor(
+			task.WithDir(dir),
+			task.WithEntrypoint(filepath.Join(dir, "Taskfile.checksum.yml")),
+			task.WithStdout(io.Discard),
+			task.WithStderr(io.Discard),
+		)
+		var checksumErr *errors.TaskfileDoesNotMatchChecksum
+		require.ErrorAs(t, e.Setup(), &checksumErr)
+		assert.Equal(t, checksum, checksumErr.ActualChecksum)
+	})
+}
+
 func TestSupportedFileNames(t *testing.T) {
 	t.Parallel()
 
@@ -1971,6 +2362,36 @@ task: [var-civet] echo 6
 	assert.Contains(t, buff.String(), output)
 }
 
+func TestJSTaskGlobal(t *testing.T) { // nolint:paralleltest // cannot run in parallel
+	// t.Parallel()
+
+	enableExperimentForTest(t, &experiments.Interp, 1)
+
+	const dir = "testdata/js"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+	)
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "task-global"}))
+	for _, line := range []string{
+		"3 task\n",
+		"logged [\"a\",\"b\",\"c\"]\n",
+		"warned\n",
+		"exec\n",
+		"task: [greet] echo hello js\nhello js\n",
+	} {
+		assert.Contains(t, buff.String(), line)
+	}
+
+	// task.exec and task.run are only available when interp_options allow them
+	err := e.Run(t.Context(), &task.Call{Task: "task-global-denied"})
+	require.ErrorContains(t, err, "task.exec is not allowed")
+}
+
 func TestShExecJs(t *testing.T) { // nolint:paralleltest // cannot run in parallel
 	// t.Parallel()
 
@@ -1995,6 +2416,122 @@ task: [shell] task.civet ./script.civet 1 2 3
 	assert.Contains(t, buff.String(), output)
 }
 
+func TestJSStdin(t *testing.T) { // nolint:paralleltest // cannot run in parallel
+	// t.Parallel()
+
+	enableExperimentForTest(t, &experiments.Interp, 1)
+
+	const dir = "testdata/js"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdin(strings.NewReader(strings.Repeat("0123456789abcdef\n", 64*1024))),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+	)
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "stdin"}))
+
+	// The script file reads all of stdin, leaving nothing to the command
+	assert.Contains(t, buff.String(), "task: [stdin] task.qjs ./count.js lines\n1114112 65536\n")
+	assert.Contains(t, buff.String(), "\n0\n")
+}
+
+func TestJSErrors(t *testing.T) { // nolint:paralleltest // cannot run in parallel
+	// t.Parallel()
+
+	enableExperimentForTest(t, &experiments.Interp, 1)
+
+	const dir = "testdata/js"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+	)
+	require.NoError(t, e.Setup())
+
+	err := e.Run(t.Context(), &task.Call{Task: "exit"})
+	var runErr *errors.TaskRunError
+	require.ErrorAs(t, err, &runErr)
+	assert.Equal(t, 3, runErr.TaskExitCode())
+	assert.Contains(t, buff.String(), "exit code 3\n")
+
+	buff.Reset()
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "exit-ignored"}))
+	assert.Contains(t, buff.String(), "after\n")
+
+	buff.Reset()
+	err = e.Run(t.Context(), &task.Call{Task: "throw"})
+	var jsErr *errors.TaskJSError
+	require.ErrorAs(t, err, &jsErr)
+	assert.Equal(t, "Error: boom", jsErr.Exception)
+	require.NotEmpty(t, jsErr.Stack)
+	assert.Contains(t, jsErr.Stack[0], ":2:")
+}
+
+func TestJSSandbox(t *testing.T) { // nolint:paralleltest // cannot run in parallel
+	// t.Parallel()
+
+	enableExperimentForTest(t, &experiments.Interp, 1)
+
+	const dir = "testdata/js"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+	)
+	require.NoError(t, e.Setup())
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "sandbox"}))
+	assert.Contains(t, buff.String(), "true\ntrue\ntrue\ntrue\n")
+	assert.NoFileExists(t, filepathext.SmartJoin(dir, "out.txt"))
+
+	err := e.Run(t.Context(), &task.Call{Task: "timeout"})
+	require.ErrorContains(t, err, "js: script exceeded its timeout of 100ms")
+
+	// Dynamic variables run in the sandbox of their task
+	buff.Reset()
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "var-sandbox"}))
+	assert.Contains(t, buff.String(), "hidden true\n")
+
+	// Included Taskfiles can narrow the sandbox
+	buff.Reset()
+	err = e.Run(t.Context(), &task.Call{Task: "included:narrow"})
+	require.ErrorContains(t, err, "task.exec is not allowed")
+	assert.Contains(t, buff.String(), "true\n")
+}
+
+func TestJSSandboxIncluded(t *testing.T) { // nolint:paralleltest // cannot run in parallel
+	// t.Parallel()
+
+	enableExperimentForTest(t, &experiments.Interp, 1)
+
+	const dir = "testdata/js/restricted"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	require.NoError(t, e.Setup())
+
+	// Included Taskfiles can't widen the sandbox of the main Taskfile
+	err := e.Run(t.Context(), &task.Call{Task: "included:widen-exec"})
+	require.ErrorContains(t, err, "task.exec is not allowed")
+
+	err = e.Run(t.Context(), &task.Call{Task: "included:widen-run"})
+	require.ErrorContains(t, err, "task.run is not allowed")
+	assert.NotContains(t, buff.String(), "hello")
+
+	buff.Reset()
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "included:widen-mounts"}))
+	assert.Equal(t, "true\nallowed\n", buff.String())
+}
+
 func TestSsh(t *testing.T) {
 	host := "127.0.0.1:10022"
 	_, err := net.DialTimeout("tcp", host, time.Second)
@@ -2046,6 +2583,286 @@ func TestSsh(t *testing.T) {
 	}
 }
 
+func TestSshHosts(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	t.Parallel()
+
+	const dir = "testdata/ssh"
+	var stdout, stderr bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&stdout),
+		task.WithStderr(&stderr),
+	)
+	require.NoError(t, e.Setup())
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "hosts", Vars: vars}))
+	assert.Contains(t, stdout.String(), "[first] root\n")
+	assert.Contains(t, stdout.String(), "[second] root\n")
+	assert.Contains(t, stderr.String(), "task: [hosts] first: ok\n")
+	assert.Contains(t, stderr.String(), "task: [hosts] second: ok\n")
+	stdout.Reset()
+	stderr.Reset()
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "hosts-for", Vars: vars}))
+	assert.Contains(t, stdout.String(), "[first] hosts-for\n")
+	assert.Contains(t, stdout.String(), "[second] hosts-for\n")
+	stdout.Reset()
+	stderr.Reset()
+
+	err = e.Run(t.Context(), &task.Call{Task: "hosts-fail", Vars: vars})
+	var runErr *errors.TaskRunError
+	require.ErrorAs(t, err, &runErr)
+	var hostsErr *errors.TaskSSHHostsError
+	require.ErrorAs(t, runErr.Err, &hostsErr)
+	assert.Equal(t, []string{"first", "second"}, hostsErr.FailedHosts)
+	var commandErr *errors.TaskSSHCommandError
+	require.ErrorAs(t, hostsErr, &commandErr)
+	assert.Equal(t, "exit 3", commandErr.Command)
+	assert.Equal(t, 3, runErr.TaskExitCode())
+	stdout.Reset()
+	stderr.Reset()
+
+	// Hosts inherit the credentials and uploads of their mapping
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "hosts-inherit", Vars: vars}))
+	assert.Contains(t, stderr.String(), "task: [hosts-inherit] first: ok\n")
+
+	// Hosts can't overwrite each other's downloads
+	err = e.Run(t.Context(), &task.Call{Task: "hosts-downloads", Vars: vars})
+	require.ErrorContains(t, err, `ssh: hosts "first" and "second" download to the same target "./out"`)
+}
+
+func TestSshJumpInherit(t *testing.T) {
+	t.Parallel()
+
+	e := task.NewExecutor(task.WithDir("testdata/ssh"))
+	require.NoError(t, e.Setup())
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: "127.0.0.1:10022"})
+
+	// Jump hosts check the host keys and time out like the host they lead to
+	compiled, err := e.CompiledTask(&task.Call{Task: "jump-inherit", Vars: vars})
+	require.NoError(t, err)
+	require.Len(t, compiled.Ssh.Jump, 2)
+	for _, jump := range compiled.Ssh.Jump {
+		assert.Equal(t, []string{"./known_hosts"}, jump.KnownHosts, jump.Addr)
+		assert.True(t, jump.Tofu, jump.Addr)
+	}
+	assert.Equal(t, 3, compiled.Ssh.Jump[0].Timeout)
+	assert.Equal(t, 5, compiled.Ssh.Jump[1].Timeout)
+}
+
+func TestSshTransfers(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	t.Parallel()
+
+	const dir = "testdata/ssh"
+	var stdout, stderr bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&stdout),
+		task.WithStderr(&stderr),
+	)
+	require.NoError(t, e.Setup())
+
+	target := t.TempDir()
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+	vars.Set("TARGET", ast.Var{Value: target})
+
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "transfers", Vars: vars}))
+	assert.Equal(t, "Taskfile.include.yaml\nTaskfile.yaml\ndocker-compose.yaml\n", stdout.String())
+	for _, name := range []string{"Taskfile.include.yaml", "Taskfile.yaml", "docker-compose.yaml"} {
+		assert.FileExists(t, filepath.Join(target, name))
+	}
+
+	// Uploads matching nothing fail the task before its commands run
+	stdout.Reset()
+	err = e.Run(t.Context(), &task.Call{Task: "transfers-missing", Vars: vars})
+	require.ErrorContains(t, err, `ssh: no files match the upload "./*.missing"`)
+	assert.Empty(t, stdout.String())
+}
+
+func TestSshUpToDate(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	t.Parallel()
+
+	const dir = "testdata/ssh"
+	var stdout, stderr bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithTempDir(task.TempDir{
+			Remote:      t.TempDir(),
+			Fingerprint: t.TempDir(),
+		}),
+		task.WithStdout(&stdout),
+		task.WithStderr(&stderr),
+	)
+	require.NoError(t, e.Setup())
+
+	run := func(name, cmd string) string {
+		t.Helper()
+		vars := ast.NewVars()
+		vars.Set("HOST", ast.Var{Value: host})
+		vars.Set("CMD", ast.Var{Value: cmd})
+		stdout.Reset()
+		require.NoError(t, e.Run(context.Background(), &task.Call{Task: name, Vars: vars}))
+		return stdout.String()
+	}
+
+	run("remote", "rm -rf /tmp/task-ssh-status /tmp/task-ssh-sources && mkdir /tmp/task-ssh-sources && echo a > /tmp/task-ssh-sources/a.txt")
+	t.Cleanup(func() { run("remote", "rm -rf /tmp/task-ssh-status /tmp/task-ssh-sources") })
+
+	assert.Equal(t, "ran\n", run("status", ""))
+	assert.Equal(t, "", run("status", ""))
+
+	assert.Equal(t, "ran\n", run("sources", ""))
+	assert.Equal(t, "", run("sources", ""))
+	run("remote", "echo b >> /tmp/task-ssh-sources/a.txt")
+	assert.Equal(t, "ran\n", run("sources", ""))
+	assert.Equal(t, "", run("sources", ""))
+	run("remote", "rm /tmp/task-ssh-sources/out")
+	assert.Equal(t, "ran\n", run("sources", ""))
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+	require.NoError(t, e.Status(t.Context(), &task.Call{Task: "status", Vars: vars}, &task.Call{Task: "sources", Vars: vars}))
+}
+
+func TestSshForwards(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	t.Parallel()
+
+	const dir = "testdata/ssh"
+	var stdout, stderr bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&stdout),
+		task.WithStderr(&stderr),
+	)
+	require.NoError(t, e.Setup())
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "forward", Vars: vars}))
+
+	var addr, port string
+	_, err = fmt.Sscan(stdout.String(), &addr, &port)
+	require.NoError(t, err)
+	assert.Equal(t, net.JoinHostPort("127.0.0.1", port), addr)
+	assert.NotEqual(t, "0", port)
+	// Only the commands are templated with the forwards, the rest of the task
+	// is kept as compiled
+	assert.Contains(t, stderr.String(), "task: [forwarded] echo "+port+"\n")
+
+	// The forward is closed with the task
+	_, err = net.Dial("tcp", addr)
+	assert.Error(t, err)
+
+	// Forwards aren't opened for tasks which don't run
+	vars.Set("ENV", ast.Var{Value: "dev"})
+	err = e.Run(t.Context(), &task.Call{Task: "forward-unreachable", Vars: vars})
+	var notAllowedErr *errors.TaskNotAllowedVarsError
+	assert.ErrorAs(t, err, &notAllowedErr)
+}
+
+func TestSshCancel(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	t.Parallel()
+
+	const dir = "testdata/ssh"
+	var buff bytes.Buffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	require.NoError(t, e.Setup())
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+	vars.Set("CMD", ast.Var{Value: "sleep 60"})
+	ctx, cancel := context.WithTimeout(t.Context(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	require.Error(t, e.Run(ctx, &task.Call{Task: "remote", Vars: vars}))
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestSshInterrupt(t *testing.T) {
+	host := "127.0.0.1:10022"
+	_, err := net.DialTimeout("tcp", host, time.Second)
+	if err != nil {
+		t.Skipf("host %q is unreachable, skip", host)
+		return
+	}
+
+	const dir = "testdata/ssh"
+	var buff SyncBuffer
+	e := task.NewExecutor(
+		task.WithDir(dir),
+		task.WithStdout(&buff),
+		task.WithStderr(&buff),
+		task.WithSilent(true),
+	)
+	require.NoError(t, e.Setup())
+	e.InterceptInterruptSignals()
+
+	vars := ast.NewVars()
+	vars.Set("HOST", ast.Var{Value: host})
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(os.Interrupt)
+	}()
+
+	// The remote command is interrupted, but not its deferred cleanup
+	start := time.Now()
+	require.Error(t, e.Run(t.Context(), &task.Call{Task: "interrupted", Vars: vars}))
+	assert.Less(t, time.Since(start), 30*time.Second)
+	assert.Contains(t, buff.buf.String(), "deferred\n")
+
+	// Later runs aren't interrupted
+	vars.Set("CMD", ast.Var{Value: "sleep 1"})
+	require.NoError(t, e.Run(t.Context(), &task.Call{Task: "remote", Vars: vars}))
+}
+
 func TestIf(t *testing.T) {
 	t.Parallel()
 
@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"strings"
+
 	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/errors"
@@ -22,6 +24,11 @@ type Cmd struct {
 	Defer       bool
 	Platforms   []*Platform
 	Interp      string
+	// Plugin is the plugin export the command calls, as <plugin>.<export>.
+	Plugin string
+	// Input is the input of the plugin export, a string passed as is or any
+	// other value encoded as JSON.
+	Input any
 }
 
 func (c *Cmd) DeepCopy() *Cmd {
@@ -42,6 +49,8 @@ func (c *Cmd) DeepCopy() *Cmd {
 		Defer:       c.Defer,
 		Platforms:   deepcopy.Slice(c.Platforms),
 		Interp:      c.Interp,
+		Plugin:      c.Plugin,
+		Input:       c.Input,
 	}
 }
 
@@ -72,6 +81,8 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 			Platforms   []*Platform
 			Ssh         *Ssh
 			Interp      string
+			Plugin      string
+			Input       any
 		}
 		if err := node.Decode(&cmdStruct); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
@@ -96,6 +107,16 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 				c.Silent = cmdStruct.Defer.Silent
 				return nil
 			}
+
+			// A deferred plugin call
+			if cmdStruct.Defer.Plugin != "" {
+				c.Defer = true
+				c.Plugin = cmdStruct.Defer.Plugin
+				c.Input = cmdStruct.Defer.Input
+				c.If = cmdStruct.If
+				c.Silent = cmdStruct.Defer.Silent
+				return nil
+			}
 			return nil
 		}
 
@@ -109,6 +130,21 @@ func (c *Cmd) UnmarshalYAML(node *yaml.Node) error {
 			return nil
 		}
 
+		// A plugin call
+		if cmdStruct.Plugin != "" {
+			if !strings.Contains(cmdStruct.Plugin, ".") {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid plugin call, use <plugin>.<export>", cmdStruct.Plugin)
+			}
+			c.Plugin = cmdStruct.Plugin
+			c.Input = cmdStruct.Input
+			c.If = cmdStruct.If
+			c.For = cmdStruct.For
+			c.Silent = cmdStruct.Silent
+			c.IgnoreError = cmdStruct.IgnoreError
+			c.Platforms = cmdStruct.Platforms
+			return nil
+		}
+
 		// A command with additional options
 		if cmdStruct.Cmd != "" {
 			c.Cmd = cmdStruct.Cmd
@@ -11,6 +11,8 @@ type Defer struct {
 	Task   string
 	Vars   *Vars
 	Silent bool
+	Plugin string
+	Input  any
 }
 
 func (d *Defer) UnmarshalYAML(node *yaml.Node) error {
@@ -30,6 +32,8 @@ func (d *Defer) UnmarshalYAML(node *yaml.Node) error {
 			Task   string
 			Vars   *Vars
 			Silent bool
+			Plugin string
+			Input  any
 		}
 		if err := node.Decode(&deferStruct); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
@@ -38,6 +42,8 @@ func (d *Defer) UnmarshalYAML(node *yaml.Node) error {
 		d.Task = deferStruct.Task
 		d.Vars = deferStruct.Vars
 		d.Silent = deferStruct.Silent
+		d.Plugin = deferStruct.Plugin
+		d.Input = deferStruct.Input
 		return nil
 	}
 
@@ -0,0 +1,118 @@
+package ast
+
+import (
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/deepcopy"
+)
+
+// InterpOptions configure the sandbox JavaScript commands and variables run
+// in. Options set on a task replace the ones set on the Taskfile, except for
+// the tasks of included and remote Taskfiles, which can only narrow them.
+type InterpOptions struct {
+	// Mounts maps the directories of the host scripts can reach to the paths
+	// they see them at. Relative directories are relative to the directory of
+	// the task. Without them, the directory of the task and the temporary
+	// directory are mounted at their own paths.
+	Mounts map[string]string
+	// ReadOnly prevents scripts from writing to the mounts.
+	ReadOnly bool `yaml:"read_only"`
+	// MaxMemory caps the memory of the interpreter, in bytes. It is written as
+	// a size, like "64MiB".
+	MaxMemory uint64 `yaml:"max_memory"`
+	// Timeout caps how long a single script can run.
+	Timeout time.Duration
+	// Env makes the environment of the task visible to scripts, which it is
+	// unless set to false.
+	Env *bool
+	// Network allows scripts to send HTTP requests.
+	Network bool
+	// Exec allows scripts to run shell commands with task.exec, which they
+	// can unless set to false.
+	Exec *bool
+	// Run allows scripts to run tasks with task.run, which they can unless set
+	// to false.
+	Run *bool
+}
+
+func (o *InterpOptions) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var v struct {
+			Mounts    map[string]string
+			ReadOnly  bool   `yaml:"read_only"`
+			MaxMemory string `yaml:"max_memory"`
+			Timeout   time.Duration
+			Env       *bool
+			Network   bool
+			Exec      *bool
+			Run       *bool
+		}
+		if err := node.Decode(&v); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		if v.MaxMemory != "" {
+			maxMemory, err := humanize.ParseBytes(v.MaxMemory)
+			if err != nil {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage(`%q is not a valid max_memory, use a size like "64MiB"`, v.MaxMemory)
+			}
+			o.MaxMemory = maxMemory
+		}
+		if v.Timeout < 0 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("%s is not a valid timeout", v.Timeout)
+		}
+		o.Mounts = v.Mounts
+		o.ReadOnly = v.ReadOnly
+		o.Timeout = v.Timeout
+		o.Env = v.Env
+		o.Network = v.Network
+		o.Exec = v.Exec
+		o.Run = v.Run
+		return nil
+	}
+
+	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("interp_options")
+}
+
+// EnvVisible reports whether scripts see the environment of the task.
+func (o *InterpOptions) EnvVisible() bool {
+	return o == nil || o.Env == nil || *o.Env
+}
+
+// ExecAllowed reports whether scripts can run shell commands with task.exec.
+func (o *InterpOptions) ExecAllowed() bool {
+	return o == nil || o.Exec == nil || *o.Exec
+}
+
+// RunAllowed reports whether scripts can run tasks with task.run.
+func (o *InterpOptions) RunAllowed() bool {
+	return o == nil || o.Run == nil || *o.Run
+}
+
+func (o *InterpOptions) DeepCopy() *InterpOptions {
+	if o == nil {
+		return nil
+	}
+	return &InterpOptions{
+		Mounts:    deepcopy.Map(o.Mounts),
+		ReadOnly:  o.ReadOnly,
+		MaxMemory: o.MaxMemory,
+		Timeout:   o.Timeout,
+		Env:       copyBool(o.Env),
+		Network:   o.Network,
+		Exec:      copyBool(o.Exec),
+		Run:       copyBool(o.Run),
+	}
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	copied := *b
+	return &copied
+}
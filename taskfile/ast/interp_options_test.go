@@ -0,0 +1,67 @@
+package ast_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestInterpOptionsParse(t *testing.T) {
+	t.Parallel()
+
+	var task ast.Task
+	require.NoError(t, yaml.Unmarshal([]byte(`
+cmd: print(1)
+interp_options:
+  mounts:
+    dist: /dist
+  read_only: true
+  max_memory: 32MiB
+  timeout: 10s
+  env: false
+  network: true
+  exec: false
+  run: true
+`), &task))
+	env, exec, run := false, false, true
+	assert.Equal(t, &ast.InterpOptions{
+		Mounts:    map[string]string{"dist": "/dist"},
+		ReadOnly:  true,
+		MaxMemory: 32 << 20,
+		Timeout:   10 * time.Second,
+		Env:       &env,
+		Network:   true,
+		Exec:      &exec,
+		Run:       &run,
+	}, task.InterpOptions)
+	assert.False(t, task.InterpOptions.EnvVisible())
+	assert.False(t, task.InterpOptions.ExecAllowed())
+	assert.True(t, task.InterpOptions.RunAllowed())
+	assert.Equal(t, task.InterpOptions, task.DeepCopy().InterpOptions)
+
+	var tf ast.Taskfile
+	require.NoError(t, yaml.Unmarshal([]byte("version: '3'\ninterp_options:\n  read_only: true\n"), &tf))
+	assert.Equal(t, &ast.InterpOptions{ReadOnly: true}, tf.InterpOptions)
+	assert.True(t, tf.InterpOptions.EnvVisible())
+	assert.True(t, tf.InterpOptions.ExecAllowed())
+	assert.True(t, tf.InterpOptions.RunAllowed())
+}
+
+func TestInterpOptionsParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	var options ast.InterpOptions
+	err := yaml.Unmarshal([]byte("max_memory: lots\n"), &options)
+	require.ErrorContains(t, err, `"lots" is not a valid max_memory`)
+
+	err = yaml.Unmarshal([]byte("timeout: -1s\n"), &options)
+	require.ErrorContains(t, err, "-1s is not a valid timeout")
+
+	err = yaml.Unmarshal([]byte("read_only\n"), &options)
+	require.ErrorContains(t, err, "interp_options")
+}
@@ -1,12 +1,16 @@
 package ast
 
 import (
-	"encoding/json"
+	"fmt"
 	"iter"
+	"maps"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/elliotchance/orderedmap/v3"
 	"gopkg.in/yaml.v3"
 
@@ -14,6 +18,12 @@ import (
 	"github.com/go-task/task/v3/internal/deepcopy"
 )
 
+// The ways a failing lifecycle hook of a plugin can be handled
+const (
+	PluginHookFailureFatal = "fatal"
+	PluginHookFailureWarn  = "warn"
+)
+
 type (
 	Plugin struct {
 		File         string
@@ -24,6 +34,29 @@ type (
 		Rand         bool
 		Stderr       bool
 		Stdout       bool
+		// Env allows the plugin to read the environment of the tasks calling
+		// it through the task_env host function.
+		Env bool
+		// RunTasks allows the plugin to run tasks through the task_run host
+		// function.
+		RunTasks bool `yaml:"run_tasks"`
+		// Checksum pins the SHA-256 checksum of the plugin file.
+		Checksum string
+		// HookFailure is how a failing lifecycle hook of the plugin is handled:
+		// "fatal", the default, fails the task and "warn" only logs the failure.
+		HookFailure string `yaml:"hook_failure"`
+		// MaxMemory caps the memory of the plugin, in bytes. It is written as
+		// a size, like "64MiB".
+		MaxMemory uint64 `yaml:"max_memory"`
+		// Timeout caps how long a single call to the plugin can take.
+		Timeout time.Duration
+		// AllowedHosts are the hosts the plugin can send HTTP requests to.
+		// Wildcards like "*.example.com" are allowed.
+		AllowedHosts []string `yaml:"allowed_hosts"`
+		// Location and Dir are the resolved location of the plugin file and the
+		// directory of the Taskfile declaring it, set once it is read.
+		Location string
+		Dir      string
 	}
 	Plugins struct {
 		om    *orderedmap.OrderedMap[string, *Plugin]
@@ -93,6 +126,20 @@ func (plugins *Plugins) Values() iter.Seq[*Plugin] {
 	return plugins.om.Values()
 }
 
+// Merge adds the plugins of an included Taskfile, namespaced like its tasks.
+func (plugins *Plugins) Merge(other *Plugins, include *Include) error {
+	for name, plugin := range other.All() {
+		if include != nil && !include.Flatten {
+			name = taskNameWithNamespace(name, include.Namespace)
+		}
+		if _, ok := plugins.Get(name); ok {
+			return fmt.Errorf("task: Found multiple plugins named %q. Plugins of flattened Taskfiles must have unique names", name)
+		}
+		plugins.Set(name, plugin.DeepCopy())
+	}
+	return nil
+}
+
 func (plugins *Plugins) UnmarshalYAML(node *yaml.Node) error {
 	if plugins == nil || plugins.om == nil {
 		*plugins = *NewPlugins()
@@ -100,25 +147,14 @@ func (plugins *Plugins) UnmarshalYAML(node *yaml.Node) error {
 
 	switch node.Kind {
 	case yaml.SequenceNode:
-		var list []any
-		if err := node.Decode(&list); err != nil {
-			return errors.NewTaskfileDecodeError(err, node)
-		}
-		for _, item := range list {
-			switch v := item.(type) {
-			case string:
-				fn := filepath.Base(v)
-				name := strings.TrimSuffix(fn, filepath.Ext(fn))
-				plugins.Set(name, &Plugin{File: v})
-			default:
-				marshalled, _ := json.Marshal(v)
-				var unmarshalled Plugin
-				if err := json.Unmarshal(marshalled, &unmarshalled); err == nil {
-					fn := filepath.Base(unmarshalled.File)
-					name := strings.TrimSuffix(fn, filepath.Ext(fn))
-					plugins.Set(name, &unmarshalled)
-				}
+		for _, itemNode := range node.Content {
+			var v Plugin
+			if err := itemNode.Decode(&v); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
 			}
+			fn := filepath.Base(v.File)
+			name := strings.TrimSuffix(fn, filepath.Ext(fn))
+			plugins.Set(name, &v)
 		}
 		return nil
 	case yaml.MappingNode:
@@ -159,10 +195,30 @@ func (plugin *Plugin) UnmarshalYAML(node *yaml.Node) error {
 			Rand         bool
 			Stderr       bool
 			Stdout       bool
+			Env          bool
+			RunTasks     bool `yaml:"run_tasks"`
+			Checksum     string
+			HookFailure  string `yaml:"hook_failure"`
+			MaxMemory    string `yaml:"max_memory"`
+			Timeout      time.Duration
+			AllowedHosts []string `yaml:"allowed_hosts"`
 		}
 		if err := node.Decode(&v); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
+		if !slices.Contains([]string{"", PluginHookFailureFatal, PluginHookFailureWarn}, v.HookFailure) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`%q is not a valid hook_failure, use "fatal" or "warn"`, v.HookFailure)
+		}
+		if v.MaxMemory != "" {
+			maxMemory, err := humanize.ParseBytes(v.MaxMemory)
+			if err != nil {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage(`%q is not a valid max_memory, use a size like "64MiB"`, v.MaxMemory)
+			}
+			plugin.MaxMemory = maxMemory
+		}
+		if v.Timeout < 0 {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("%s is not a valid timeout", v.Timeout)
+		}
 		plugin.File = v.File
 		plugin.Mounts = v.Mounts
 		plugin.SysNanosleep = v.SysNanosleep
@@ -171,6 +227,12 @@ func (plugin *Plugin) UnmarshalYAML(node *yaml.Node) error {
 		plugin.Rand = v.Rand
 		plugin.Stderr = v.Stderr
 		plugin.Stdout = v.Stdout
+		plugin.Env = v.Env
+		plugin.RunTasks = v.RunTasks
+		plugin.Checksum = v.Checksum
+		plugin.HookFailure = v.HookFailure
+		plugin.Timeout = v.Timeout
+		plugin.AllowedHosts = v.AllowedHosts
 		return nil
 	}
 
@@ -190,5 +252,43 @@ func (plugin *Plugin) DeepCopy() *Plugin {
 		Rand:         plugin.Rand,
 		Stderr:       plugin.Stderr,
 		Stdout:       plugin.Stdout,
+		Env:          plugin.Env,
+		RunTasks:     plugin.RunTasks,
+		Checksum:     plugin.Checksum,
+		HookFailure:  plugin.HookFailure,
+		MaxMemory:    plugin.MaxMemory,
+		Timeout:      plugin.Timeout,
+		AllowedHosts: deepcopy.Slice(plugin.AllowedHosts),
+		Location:     plugin.Location,
+		Dir:          plugin.Dir,
+	}
+}
+
+// Capabilities lists what the plugin is allowed to do, for it to be audited.
+func (plugin *Plugin) Capabilities() []string {
+	var capabilities []string
+	for _, host := range slices.Sorted(maps.Keys(plugin.Mounts)) {
+		capabilities = append(capabilities, fmt.Sprintf("mount %s at %s", host, plugin.Mounts[host]))
+	}
+	for _, capability := range []struct {
+		name    string
+		granted bool
+	}{
+		{"sys_nanosleep", plugin.SysNanosleep},
+		{"sys_nanotime", plugin.SysNanotime},
+		{"sys_walltime", plugin.SysWalltime},
+		{"rand", plugin.Rand},
+		{"stderr", plugin.Stderr},
+		{"stdout", plugin.Stdout},
+		{"env", plugin.Env},
+		{"run_tasks", plugin.RunTasks},
+	} {
+		if capability.granted {
+			capabilities = append(capabilities, capability.name)
+		}
+	}
+	if len(plugin.AllowedHosts) > 0 {
+		capabilities = append(capabilities, "http to "+strings.Join(plugin.AllowedHosts, ", "))
 	}
+	return capabilities
 }
@@ -2,6 +2,7 @@ package ast_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -42,6 +43,13 @@ a:
   rand: true
   stderr: true
   stdout: true
+  run_tasks: true
+  checksum: abc123
+  hook_failure: warn
+  max_memory: 64MiB
+  timeout: 5s
+  allowed_hosts:
+    - example.com
 `,
 			&ast.Plugins{},
 			ast.NewPlugins(
@@ -54,6 +62,12 @@ a:
 					Rand:         true,
 					Stderr:       true,
 					Stdout:       true,
+					RunTasks:     true,
+					Checksum:     "abc123",
+					HookFailure:  "warn",
+					MaxMemory:    64 << 20,
+					Timeout:      5 * time.Second,
+					AllowedHosts: []string{"example.com"},
 				}},
 			),
 		},
@@ -71,11 +85,12 @@ a:
 		{
 			`
 - file: a.wasm
+  run_tasks: true
 - file: b.wasm
 `,
 			&ast.Plugins{},
 			ast.NewPlugins(
-				&ast.PluginElement{Key: "a", Value: &ast.Plugin{File: "a.wasm"}},
+				&ast.PluginElement{Key: "a", Value: &ast.Plugin{File: "a.wasm", RunTasks: true}},
 				&ast.PluginElement{Key: "b", Value: &ast.Plugin{File: "b.wasm"}},
 			),
 		},
@@ -86,3 +101,50 @@ a:
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestPluginsParseInvalidHookFailure(t *testing.T) {
+	t.Parallel()
+
+	var plugins ast.Plugins
+	err := yaml.Unmarshal([]byte("a:\n  file: a.wasm\n  hook_failure: ignore\n"), &plugins)
+	require.ErrorContains(t, err, `"ignore" is not a valid hook_failure`)
+}
+
+func TestPluginsParseInvalidMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	var plugins ast.Plugins
+	err := yaml.Unmarshal([]byte("a:\n  file: a.wasm\n  max_memory: lots\n"), &plugins)
+	require.ErrorContains(t, err, `"lots" is not a valid max_memory`)
+}
+
+func TestPluginCapabilities(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, (&ast.Plugin{File: "a.wasm", MaxMemory: 1 << 20}).Capabilities())
+	assert.Equal(t,
+		[]string{"mount /data at /mnt", "mount /tmp at /tmp", "sys_walltime", "stdout", "env", "run_tasks", "http to example.com, *.example.org"},
+		(&ast.Plugin{
+			Mounts:       map[string]string{"/tmp": "/tmp", "/data": "/mnt"},
+			SysWalltime:  true,
+			Stdout:       true,
+			Env:          true,
+			RunTasks:     true,
+			AllowedHosts: []string{"example.com", "*.example.org"},
+		}).Capabilities(),
+	)
+}
+
+func TestPluginVarParse(t *testing.T) {
+	t.Parallel()
+
+	var v ast.Var
+	require.NoError(t, yaml.Unmarshal([]byte("plugin: lint.report\ninput:\n  strict: true\n"), &v))
+	assert.Equal(t, ast.Var{Plugin: "lint.report", Input: map[string]any{"strict": true}}, v)
+
+	require.NoError(t, yaml.Unmarshal([]byte("plugin: lint.report\nlogs: true\n"), &v))
+	assert.Equal(t, ast.Var{Plugin: "lint.report", Logs: true}, v)
+
+	err := yaml.Unmarshal([]byte("plugin: lint\n"), &v)
+	require.ErrorContains(t, err, `"lint" is not a valid plugin call, use <plugin>.<export>`)
+}
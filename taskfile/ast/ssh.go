@@ -51,8 +51,26 @@ func (u *SshUpload) UnmarshalYAML(node *yaml.Node) error {
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("upload")
 }
 
+// SshForward is a tunnel opened between the local host and the SSH host for
+// the duration of the task.
+type SshForward struct {
+	// Name is used in the variables exposing the address of the forward.
+	Name    string
+	Local   string
+	Remote  string
+	Reverse bool
+}
+
+func (f *SshForward) DeepCopy() *SshForward {
+	if f == nil {
+		return nil
+	}
+	return &SshForward{Name: f.Name, Local: f.Local, Remote: f.Remote, Reverse: f.Reverse}
+}
+
 type Ssh struct {
 	Url        string
+	Name       string
 	Addr       string
 	User       string
 	Password   string
@@ -61,7 +79,20 @@ type Ssh struct {
 	KnownHosts []string
 	Timeout    int
 	Insecure   bool
-	Uploads    []SshUpload
+	// Tofu trusts the keys of the hosts missing from KnownHosts on first use,
+	// once the user accepts them.
+	Tofu    bool
+	Jump    []*Ssh
+	Uploads []SshUpload
+	// Downloads are fetched from the host once the commands succeeded. Their
+	// sources are remote paths and their targets local ones.
+	Downloads []SshUpload
+	Forwards  []SshForward
+	// Hosts fans the task out to several hosts. It is either given as a list
+	// or generated by For from the other fields used as a template.
+	Hosts    []*Ssh
+	For      *For
+	FailFast bool
 }
 
 func (s *Ssh) DeepCopy() *Ssh {
@@ -70,6 +101,7 @@ func (s *Ssh) DeepCopy() *Ssh {
 	}
 	return &Ssh{
 		Url:        s.Url,
+		Name:       s.Name,
 		Addr:       s.Addr,
 		User:       s.User,
 		Password:   s.Password,
@@ -78,10 +110,75 @@ func (s *Ssh) DeepCopy() *Ssh {
 		KnownHosts: deepcopy.Slice(s.KnownHosts),
 		Timeout:    s.Timeout,
 		Insecure:   s.Insecure,
+		Tofu:       s.Tofu,
+		Jump:       deepcopy.Slice(s.Jump),
 		Uploads:    deepcopy.Slice(s.Uploads),
+		Downloads:  deepcopy.Slice(s.Downloads),
+		Forwards:   deepcopy.Slice(s.Forwards),
+		Hosts:      deepcopy.Slice(s.Hosts),
+		For:        s.For.DeepCopy(),
+		FailFast:   s.FailFast,
+	}
+}
+
+// Inherit fills the connection settings the host doesn't set with the ones of
+// the parent it is listed in, and transfers the files of the parent before
+// its own.
+func (s *Ssh) Inherit(parent *Ssh) {
+	if s.User == "" {
+		s.User = parent.User
+	}
+	if s.Password == "" {
+		s.Password = parent.Password
+	}
+	if s.Key == "" && s.KeyPath == "" {
+		s.Key = parent.Key
+		s.KeyPath = parent.KeyPath
+	}
+	if len(s.KnownHosts) == 0 {
+		s.KnownHosts = deepcopy.Slice(parent.KnownHosts)
+	}
+	if s.Timeout == 0 {
+		s.Timeout = parent.Timeout
+	}
+	if len(s.Jump) == 0 {
+		s.Jump = deepcopy.Slice(parent.Jump)
+	}
+	s.Insecure = s.Insecure || parent.Insecure
+	s.Tofu = s.Tofu || parent.Tofu
+	s.Uploads = append(deepcopy.Slice(parent.Uploads), s.Uploads...)
+	s.Downloads = append(deepcopy.Slice(parent.Downloads), s.Downloads...)
+}
+
+// InheritJumps fills the host key checks and the timeout the jump hosts don't
+// set with the ones of the host they lead to, as OpenSSH does for ProxyJump.
+func (s *Ssh) InheritJumps() {
+	for _, jump := range s.Jump {
+		if len(jump.KnownHosts) == 0 {
+			jump.KnownHosts = deepcopy.Slice(s.KnownHosts)
+		}
+		if jump.Timeout == 0 {
+			jump.Timeout = s.Timeout
+		}
+		jump.Insecure = jump.Insecure || s.Insecure
+		jump.Tofu = jump.Tofu || s.Tofu
 	}
 }
 
+// IsFanOut returns true if the task runs on a list of hosts instead of a
+// single one.
+func (s *Ssh) IsFanOut() bool {
+	return s != nil && s.Hosts != nil
+}
+
+// HostName returns the name identifying the host in the output.
+func (s *Ssh) HostName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Addr
+}
+
 func (s *Ssh) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 	case yaml.ScalarNode:
@@ -92,9 +189,22 @@ func (s *Ssh) UnmarshalYAML(node *yaml.Node) error {
 		}
 		s.Url = url
 		return nil
+	case yaml.SequenceNode:
+		var hosts []*Ssh
+		if err := node.Decode(&hosts); err != nil {
+			return errors.NewTaskfileDecodeError(err, node)
+		}
+		for _, host := range hosts {
+			if len(host.Forwards) > 0 {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("ssh forwards cannot be used with several hosts")
+			}
+		}
+		s.Hosts = hosts
+		return nil
 	case yaml.MappingNode:
 		var ssh struct {
 			Url        string
+			Name       string
 			Addr       string
 			User       string
 			Password   string
@@ -103,12 +213,25 @@ func (s *Ssh) UnmarshalYAML(node *yaml.Node) error {
 			KnownHosts []string
 			Timeout    int
 			Insecure   bool
+			Tofu       bool
+			Jump       []*Ssh
 			Uploads    []SshUpload
+			Downloads  []SshUpload
+			Forwards   []SshForward
+			Hosts      []*Ssh
+			For        *For
+			FailFast   bool `yaml:"fail_fast"`
 		}
 		if err := node.Decode(&ssh); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
 		}
-		*s = ssh
+		if len(ssh.Hosts) > 0 && ssh.For != nil {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("ssh cannot have both hosts and for")
+		}
+		if len(ssh.Forwards) > 0 && (len(ssh.Hosts) > 0 || ssh.For != nil) {
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage("ssh forwards cannot be used with several hosts")
+		}
+		*s = Ssh(ssh)
 		return nil
 	}
 	return errors.NewTaskfileDecodeError(nil, node).WithTypeMessage("ssh")
@@ -0,0 +1,178 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestSshParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		content  string
+		v        any
+		expected any
+	}{
+		{
+			`//root@example.com`,
+			&ast.Ssh{},
+			&ast.Ssh{Url: "//root@example.com"},
+		},
+		{
+			`
+addr: example.com
+user: root
+tofu: true
+jump:
+  - //admin@bastion
+  - addr: bastion2
+`,
+			&ast.Ssh{},
+			&ast.Ssh{
+				Addr: "example.com",
+				User: "root",
+				Tofu: true,
+				Jump: []*ast.Ssh{
+					{Url: "//admin@bastion"},
+					{Addr: "bastion2"},
+				},
+			},
+		},
+		{
+			`
+- //root@web1
+- name: web2
+  addr: web2
+`,
+			&ast.Ssh{},
+			&ast.Ssh{
+				Hosts: []*ast.Ssh{
+					{Url: "//root@web1"},
+					{Name: "web2", Addr: "web2"},
+				},
+			},
+		},
+		{
+			`
+for: { var: HOSTS }
+url: //root@{{.ITEM}}
+fail_fast: true
+`,
+			&ast.Ssh{},
+			&ast.Ssh{
+				Url:      "//root@{{.ITEM}}",
+				For:      &ast.For{Var: "HOSTS"},
+				FailFast: true,
+			},
+		},
+		{
+			`
+url: //root@example.com
+uploads:
+  - ./dist/**/*.js:/opt/app
+downloads:
+  - source: /opt/app/logs
+    target: ./logs
+`,
+			&ast.Ssh{},
+			&ast.Ssh{
+				Url:       "//root@example.com",
+				Uploads:   []ast.SshUpload{{Source: "./dist/**/*.js", Target: "/opt/app"}},
+				Downloads: []ast.SshUpload{{Source: "/opt/app/logs", Target: "./logs"}},
+			},
+		},
+		{
+			`
+url: //root@bastion
+forwards:
+  - name: db
+    local: 0
+    remote: db.internal:5432
+  - local: /tmp/docker.sock
+    remote: /var/run/docker.sock
+  - local: 8080
+    remote: 9090
+    reverse: true
+`,
+			&ast.Ssh{},
+			&ast.Ssh{
+				Url: "//root@bastion",
+				Forwards: []ast.SshForward{
+					{Name: "db", Local: "0", Remote: "db.internal:5432"},
+					{Local: "/tmp/docker.sock", Remote: "/var/run/docker.sock"},
+					{Local: "8080", Remote: "9090", Reverse: true},
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		err := yaml.Unmarshal([]byte(test.content), test.v)
+		require.NoError(t, err)
+		assert.Equal(t, test.expected, test.v)
+	}
+}
+
+func TestSshParseHostsAndFor(t *testing.T) {
+	t.Parallel()
+
+	content := `
+for: { var: HOSTS }
+hosts:
+  - //root@web1
+`
+	var ssh ast.Ssh
+	require.Error(t, yaml.Unmarshal([]byte(content), &ssh))
+}
+
+func TestSshParseForwardsWithHosts(t *testing.T) {
+	t.Parallel()
+
+	for _, content := range []string{
+		`
+for: { var: HOSTS }
+url: //root@{{.ITEM}}
+forwards:
+  - local: 0
+    remote: 5432
+`,
+		`
+- url: //root@web1
+  forwards:
+    - local: 0
+      remote: 5432
+`,
+	} {
+		var ssh ast.Ssh
+		require.Error(t, yaml.Unmarshal([]byte(content), &ssh))
+	}
+}
+
+func TestSshInheritJumps(t *testing.T) {
+	t.Parallel()
+
+	content := `
+addr: example.com
+knownhosts: [/etc/ssh/known_hosts]
+timeout: 5
+insecure: true
+tofu: true
+jump:
+  - addr: bastion1
+  - addr: bastion2
+    knownhosts: [./known_hosts]
+    timeout: 10
+`
+	var ssh ast.Ssh
+	require.NoError(t, yaml.Unmarshal([]byte(content), &ssh))
+	ssh.InheritJumps()
+
+	assert.Equal(t, []*ast.Ssh{
+		{Addr: "bastion1", KnownHosts: []string{"/etc/ssh/known_hosts"}, Timeout: 5, Insecure: true, Tofu: true},
+		{Addr: "bastion2", KnownHosts: []string{"./known_hosts"}, Timeout: 10, Insecure: true, Tofu: true},
+	}, ssh.Jump)
+}
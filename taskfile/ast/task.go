@@ -44,6 +44,7 @@ type Task struct {
 	Platforms     []*Platform
 	Ssh           *Ssh
 	SshClient     *taskSsh.SshClient
+	InterpOptions *InterpOptions
 	Watch         bool
 	Location      *Location
 	// Populated during merging
@@ -149,6 +150,7 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 			Ssh           *Ssh
 			Requires      *Requires
 			Watch         bool
+			InterpOptions *InterpOptions `yaml:"interp_options"`
 		}
 		if err := node.Decode(&task); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
@@ -189,6 +191,7 @@ func (t *Task) UnmarshalYAML(node *yaml.Node) error {
 		t.Ssh = task.Ssh
 		t.Requires = task.Requires
 		t.Watch = task.Watch
+		t.InterpOptions = task.InterpOptions
 		return nil
 	}
 
@@ -233,6 +236,7 @@ func (t *Task) DeepCopy() *Task {
 		Platforms:            deepcopy.Slice(t.Platforms),
 		Ssh:                  t.Ssh.DeepCopy(),
 		SshClient:            nil,
+		InterpOptions:        t.InterpOptions.DeepCopy(),
 		Location:             t.Location.DeepCopy(),
 		Requires:             t.Requires.DeepCopy(),
 		Namespace:            t.Namespace,
@@ -35,6 +35,9 @@ type Taskfile struct {
 	Dotenv   []string
 	Run      string
 	Interval time.Duration
+	// InterpOptions are the interp_options of the tasks which don't set
+	// theirs. Only the ones of the main Taskfile are used.
+	InterpOptions *InterpOptions
 }
 
 // Merge merges the second Taskfile into the first
@@ -63,6 +66,9 @@ func (t1 *Taskfile) Merge(t2 *Taskfile, include *Include) error {
 	if t1.Tasks == nil {
 		t1.Tasks = NewTasks()
 	}
+	if err := t1.Plugins.Merge(t2.Plugins, include); err != nil {
+		return err
+	}
 	t1.Vars.Merge(t2.Vars, include)
 	t1.Env.Merge(t2.Env, include)
 	return t1.Tasks.Merge(t2.Tasks, include, t1.Vars)
@@ -72,20 +78,21 @@ func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 	switch node.Kind {
 	case yaml.MappingNode:
 		var taskfile struct {
-			Version  *semver.Version
-			Output   Output
-			Method   string
-			Includes *Includes
-			Plugins  *Plugins
-			Set      []string
-			Shopt    []string
-			Vars     *Vars
-			Env      *Vars
-			Tasks    *Tasks
-			Silent   bool
-			Dotenv   []string
-			Run      string
-			Interval time.Duration
+			Version       *semver.Version
+			Output        Output
+			Method        string
+			Includes      *Includes
+			Plugins       *Plugins
+			Set           []string
+			Shopt         []string
+			Vars          *Vars
+			Env           *Vars
+			Tasks         *Tasks
+			Silent        bool
+			Dotenv        []string
+			Run           string
+			Interval      time.Duration
+			InterpOptions *InterpOptions `yaml:"interp_options"`
 		}
 		if err := node.Decode(&taskfile); err != nil {
 			return errors.NewTaskfileDecodeError(err, node)
@@ -107,6 +114,7 @@ func (tf *Taskfile) UnmarshalYAML(node *yaml.Node) error {
 		tf.Dotenv = taskfile.Dotenv
 		tf.Run = taskfile.Run
 		tf.Interval = taskfile.Interval
+		tf.InterpOptions = taskfile.InterpOptions
 		if tf.Includes == nil {
 			tf.Includes = NewIncludes()
 		}
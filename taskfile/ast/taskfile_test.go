@@ -24,6 +24,13 @@ vars:
 `
 		yamlDeferredCall = `defer: { task: some_task, vars: { PARAM1: "var" } }`
 		yamlDeferredCmd  = `defer: echo 'test'`
+		yamlPluginCall   = `
+plugin: fmt.check
+input:
+  files: [a.go]
+ignore_error: true
+`
+		yamlDeferredPluginCall = `defer: { plugin: fmt.cleanup, input: all }`
 	)
 	tests := []struct {
 		content  string
@@ -77,6 +84,20 @@ vars:
 				Defer: true,
 			},
 		},
+		{
+			yamlPluginCall,
+			&ast.Cmd{},
+			&ast.Cmd{
+				Plugin:      "fmt.check",
+				Input:       map[string]any{"files": []any{"a.go"}},
+				IgnoreError: true,
+			},
+		},
+		{
+			yamlDeferredPluginCall,
+			&ast.Cmd{},
+			&ast.Cmd{Plugin: "fmt.cleanup", Input: "all", Defer: true},
+		},
 		{
 			yamlDep,
 			&ast.Dep{},
@@ -110,3 +131,11 @@ vars:
 		assert.Equal(t, test.expected, test.v)
 	}
 }
+
+func TestCmdParseInvalidPluginCall(t *testing.T) {
+	t.Parallel()
+
+	var cmd ast.Cmd
+	err := yaml.Unmarshal([]byte("plugin: fmt"), &cmd)
+	require.ErrorContains(t, err, `"fmt" is not a valid plugin call, use <plugin>.<export>`)
+}
@@ -150,6 +150,16 @@ func (t1 *Tasks) Merge(t2 *Tasks, include *Include, includedTaskfileVars *Vars)
 				}
 			}
 
+			// Add namespaces to plugin methods and calls
+			if strings.Contains(task.Method, ".") {
+				task.Method = taskNameWithNamespace(task.Method, include.Namespace)
+			}
+			for _, cmd := range task.Cmds {
+				if cmd != nil && cmd.Plugin != "" {
+					cmd.Plugin = taskNameWithNamespace(cmd.Plugin, include.Namespace)
+				}
+			}
+
 			// Add namespaces to task aliases
 			for i, alias := range task.Aliases {
 				task.Aliases[i] = taskNameWithNamespace(alias, include.Namespace)
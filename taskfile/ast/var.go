@@ -1,6 +1,8 @@
 package ast
 
 import (
+	"strings"
+
 	"gopkg.in/yaml.v3"
 
 	"github.com/go-task/task/v3/errors"
@@ -14,6 +16,12 @@ type Var struct {
 	Interp string
 	Ref    string
 	Dir    string
+	// Plugin is the plugin export whose output is the value of the variable,
+	// as <plugin>.<export>, called with Input. Logs captures the stderr and
+	// the logs of the plugin into the variable too.
+	Plugin string
+	Input  any
+	Logs   bool
 }
 
 func (v *Var) UnmarshalYAML(node *yaml.Node) error {
@@ -39,8 +47,24 @@ func (v *Var) UnmarshalYAML(node *yaml.Node) error {
 			v.Ref = m.Ref
 			v.Value = m.Map
 			return nil
+		case "plugin":
+			var m struct {
+				Plugin string
+				Input  any
+				Logs   bool
+			}
+			if err := node.Decode(&m); err != nil {
+				return errors.NewTaskfileDecodeError(err, node)
+			}
+			if !strings.Contains(m.Plugin, ".") {
+				return errors.NewTaskfileDecodeError(nil, node).WithMessage("%q is not a valid plugin call, use <plugin>.<export>", m.Plugin)
+			}
+			v.Plugin = m.Plugin
+			v.Input = m.Input
+			v.Logs = m.Logs
+			return nil
 		default:
-			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`%q is not a valid variable type. Try "sh", "ref", "map" or using a scalar value`, key)
+			return errors.NewTaskfileDecodeError(nil, node).WithMessage(`%q is not a valid variable type. Try "sh", "ref", "map", "plugin" or using a scalar value`, key)
 		}
 	default:
 		var value any
@@ -7,9 +7,10 @@ type (
 	// designed to be embedded in other node types so that this boilerplate code
 	// does not need to be repeated.
 	baseNode struct {
-		parent   Node
-		dir      string
-		checksum string
+		parent       Node
+		dir          string
+		checksum     string
+		contentTypes []string
 	}
 )
 
@@ -39,6 +40,14 @@ func WithChecksum(checksum string) NodeOption {
 	}
 }
 
+// WithContentTypes sets the content types allowed for a remote node, in place
+// of the ones of Taskfiles.
+func WithContentTypes(contentTypes ...string) NodeOption {
+	return func(node *baseNode) {
+		node.contentTypes = contentTypes
+	}
+}
+
 func (node *baseNode) Parent() Node {
 	return node.parent
 }
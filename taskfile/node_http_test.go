@@ -1,6 +1,9 @@
 package taskfile
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -47,3 +50,26 @@ func TestHTTPNode_CacheKey(t *testing.T) {
 		assert.Equal(t, tt.expectedKey, key)
 	}
 }
+
+func TestRemoteExistsContentTypes(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/plugin.wasm" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/wasm")
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL + "/plugin.wasm")
+	require.NoError(t, err)
+
+	// Plugins aren't Taskfiles
+	_, err = RemoteExists(t.Context(), *u)
+	require.Error(t, err)
+
+	found, err := RemoteExists(t.Context(), *u, pluginContentTypes...)
+	require.NoError(t, err)
+	assert.Equal(t, u.String(), found.String())
+}
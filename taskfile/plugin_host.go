@@ -0,0 +1,181 @@
+package taskfile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	extism "github.com/extism/go-sdk"
+
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// Plugins reach Task through host functions imported from the
+// "extism:host/user" module. Strings are passed as pointers to Extism memory
+// blocks (i64), and the null pointer stands for a missing value:
+//
+//	task_var(name ptr) ptr
+//		Returns the value of a variable of the template calling the plugin, JSON
+//		encoded unless it is a string, or 0 if it isn't set.
+//	task_env(name ptr) ptr
+//		Returns the value of an environment variable as the task calling the
+//		plugin sees it, including the Taskfile env and dotenv files, or 0 if it
+//		isn't set. The plugin must set env: true.
+//	task_log(level i32, message ptr)
+//		Writes a message to the Task logger, next to the output of the task
+//		calling the plugin. The level is one of 0 (verbose), 1 (info),
+//		2 (warning) and 3 (error).
+//	task_run(name ptr, vars ptr) ptr
+//		Runs a task, with vars being 0 or a JSON object, and returns 0 once it
+//		succeeded or the error message. The plugin must set run_tasks: true.
+//
+// Host functions panic when the plugin passes an invalid pointer, which makes
+// the plugin call fail.
+
+// PluginLogLevel is the level of a message logged by a plugin.
+type PluginLogLevel int
+
+const (
+	PluginLogVerbose PluginLogLevel = iota
+	PluginLogInfo
+	PluginLogWarning
+	PluginLogError
+)
+
+// PluginHost is the part of Task plugins can reach through host functions.
+type PluginHost interface {
+	// Log writes a message to the Task logger, or to the stderr of the call
+	// when it has one.
+	Log(ctx context.Context, level PluginLogLevel, message string)
+	// LookupEnv returns the value of an environment variable as the task
+	// calling the plugin sees it.
+	LookupEnv(ctx context.Context, name string) (string, bool)
+	// RunTask runs a task with the given variables.
+	RunTask(ctx context.Context, name string, vars map[string]any) error
+}
+
+type pluginVarsKey struct{}
+
+// withPluginVars attaches the variables of the template calling a plugin to
+// the context of the call, where task_var reads them.
+func withPluginVars(ctx context.Context, vars map[string]any) context.Context {
+	return context.WithValue(ctx, pluginVarsKey{}, vars)
+}
+
+type pluginOutputKey struct{}
+
+// WithPluginOutput attaches the writers the plugins called with the context
+// write their stdout and stderr to, when they are allowed to. Calls without
+// them write to the stdout and stderr of the process.
+func WithPluginOutput(ctx context.Context, stdout, stderr io.Writer) context.Context {
+	return context.WithValue(ctx, pluginOutputKey{}, [2]io.Writer{stdout, stderr})
+}
+
+// PluginStderr returns the stderr attached to the context of a plugin call,
+// if any.
+func PluginStderr(ctx context.Context) (io.Writer, bool) {
+	output, ok := ctx.Value(pluginOutputKey{}).([2]io.Writer)
+	return output[1], ok
+}
+
+// pluginOutput returns the writers of a plugin call.
+func pluginOutput(ctx context.Context) (stdout, stderr io.Writer) {
+	if output, ok := ctx.Value(pluginOutputKey{}).([2]io.Writer); ok {
+		return output[0], output[1]
+	}
+	return os.Stdout, os.Stderr
+}
+
+// pluginHostFunctions returns the host functions available to the plugin.
+func pluginHostFunctions(name string, plugin *ast.Plugin, host PluginHost) []extism.HostFunction {
+	ptr := extism.ValueTypePTR
+	return []extism.HostFunction{
+		extism.NewHostFunctionWithStack("task_var", func(ctx context.Context, p *extism.CurrentPlugin, stack []uint64) {
+			vars, _ := ctx.Value(pluginVarsKey{}).(map[string]any)
+			value, ok := vars[readPluginString(p, stack[0])]
+			if !ok || value == nil {
+				stack[0] = 0
+				return
+			}
+			s, ok := value.(string)
+			if !ok {
+				b, err := json.Marshal(value)
+				if err != nil {
+					panic(err)
+				}
+				s = string(b)
+			}
+			stack[0] = writePluginString(p, s)
+		}, []extism.ValueType{ptr}, []extism.ValueType{ptr}),
+
+		extism.NewHostFunctionWithStack("task_env", func(ctx context.Context, p *extism.CurrentPlugin, stack []uint64) {
+			if !plugin.Env {
+				panic(fmt.Errorf("task: Plugin %q is not allowed to read the environment, set env: true to allow it", name))
+			}
+			key := readPluginString(p, stack[0])
+			var value string
+			var ok bool
+			if host != nil {
+				value, ok = host.LookupEnv(ctx, key)
+			} else {
+				value, ok = os.LookupEnv(key)
+			}
+			if !ok {
+				stack[0] = 0
+				return
+			}
+			stack[0] = writePluginString(p, value)
+		}, []extism.ValueType{ptr}, []extism.ValueType{ptr}),
+
+		extism.NewHostFunctionWithStack("task_log", func(ctx context.Context, p *extism.CurrentPlugin, stack []uint64) {
+			message := readPluginString(p, stack[1])
+			if host != nil {
+				host.Log(ctx, PluginLogLevel(extism.DecodeI32(stack[0])), message)
+			}
+		}, []extism.ValueType{extism.ValueTypeI32, ptr}, []extism.ValueType{}),
+
+		extism.NewHostFunctionWithStack("task_run", func(ctx context.Context, p *extism.CurrentPlugin, stack []uint64) {
+			taskName := readPluginString(p, stack[0])
+			var vars map[string]any
+			if stack[1] != 0 {
+				if err := json.Unmarshal([]byte(readPluginString(p, stack[1])), &vars); err != nil {
+					stack[0] = writePluginString(p, fmt.Sprintf("task: invalid variables for task %q: %v", taskName, err))
+					return
+				}
+			}
+
+			var err error
+			switch {
+			case !plugin.RunTasks:
+				err = fmt.Errorf("task: Plugin %q is not allowed to run tasks, set run_tasks: true to allow it", name)
+			case host == nil:
+				err = fmt.Errorf("task: Plugin %q can't run tasks outside of an executor", name)
+			default:
+				err = host.RunTask(ctx, taskName, vars)
+			}
+			if err != nil {
+				stack[0] = writePluginString(p, err.Error())
+				return
+			}
+			stack[0] = 0
+		}, []extism.ValueType{ptr, ptr}, []extism.ValueType{ptr}),
+	}
+}
+
+func readPluginString(p *extism.CurrentPlugin, offset uint64) string {
+	s, err := p.ReadString(offset)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func writePluginString(p *extism.CurrentPlugin, s string) uint64 {
+	offset, err := p.WriteString(s)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
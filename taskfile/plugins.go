@@ -0,0 +1,261 @@
+package taskfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	extism "github.com/extism/go-sdk"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/sys"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/internal/wasmext"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+// pluginCacheDir is where compiled plugins are cached, in the temp dir.
+const pluginCacheDir = "plugins"
+
+// Plugins are the plugins loaded from a Taskfile, by name.
+type Plugins map[string]*pluginPool
+
+// pluginPool keeps the idle instances of a compiled plugin, so that concurrent
+// calls each get an instance of their own instead of waiting for a single one.
+// Instances don't share their memory, so plugins can't keep state from a call
+// to another.
+type pluginPool struct {
+	compiled *extism.CompiledPlugin
+	config   extism.PluginInstanceConfig
+	plugin   *ast.Plugin
+	exports  map[string]bool
+	mutex    sync.Mutex
+	idle     []*pluginInstance
+	closed   bool
+}
+
+// pluginInstance is an instance of a plugin, whose stdout and stderr are
+// connected to the writers of the call it runs.
+type pluginInstance struct {
+	*extism.Plugin
+	stdout, stderr pluginWriter
+	memory         *wasmext.MaxMemory
+}
+
+// pluginWriter forwards the writes of a plugin instance to the writer of its
+// current call. An instance only runs a call at a time.
+type pluginWriter struct {
+	w io.Writer
+}
+
+func (w *pluginWriter) Write(p []byte) (int, error) {
+	if w.w == nil {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+func newPluginPool(ctx context.Context, compiled *extism.CompiledPlugin, config extism.PluginInstanceConfig, plugin *ast.Plugin) (*pluginPool, error) {
+	pool := &pluginPool{
+		compiled: compiled,
+		config:   config,
+		plugin:   plugin,
+		exports:  map[string]bool{},
+	}
+	instance, err := pool.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for name := range instance.Module().ExportedFunctions() {
+		pool.exports[name] = true
+	}
+	pool.put(instance)
+	return pool, nil
+}
+
+func (pool *pluginPool) get(ctx context.Context) (*pluginInstance, error) {
+	pool.mutex.Lock()
+	if n := len(pool.idle); n > 0 {
+		instance := pool.idle[n-1]
+		pool.idle = pool.idle[:n-1]
+		pool.mutex.Unlock()
+		return instance, nil
+	}
+	pool.mutex.Unlock()
+
+	instance := &pluginInstance{memory: wasmext.NewMaxMemory(pool.plugin.MaxMemory)}
+	config := pool.config
+	if config.ModuleConfig == nil {
+		config.ModuleConfig = wazero.NewModuleConfig()
+	}
+	if pool.plugin.Stdout {
+		config.ModuleConfig = config.ModuleConfig.WithStdout(&instance.stdout)
+	}
+	if pool.plugin.Stderr {
+		config.ModuleConfig = config.ModuleConfig.WithStderr(&instance.stderr)
+	}
+	var err error
+	if instance.Plugin, err = pool.compiled.Instance(instance.memory.WithContext(ctx), config); err != nil {
+		return nil, err
+	}
+	if instance.memory.Exceeded() {
+		_ = instance.Close(context.Background())
+		return nil, fmt.Errorf("its memory exceeds its max_memory of %s", humanize.IBytes(pool.plugin.MaxMemory))
+	}
+	return instance, nil
+}
+
+// put returns an instance to the pool, which keeps one per CPU at most.
+func (pool *pluginPool) put(instance *pluginInstance) {
+	instance.stdout.w, instance.stderr.w = nil, nil
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if !pool.closed && len(pool.idle) < runtime.GOMAXPROCS(0) {
+		pool.idle = append(pool.idle, instance)
+		return
+	}
+	_ = instance.Close(context.Background())
+}
+
+// close closes the idle instances and the compiled plugin. Instances still in
+// use are closed when they are put back.
+func (pool *pluginPool) close(ctx context.Context) error {
+	pool.mutex.Lock()
+	idle := pool.idle
+	pool.idle, pool.closed = nil, true
+	pool.mutex.Unlock()
+
+	var errs []error
+	for _, instance := range idle {
+		if err := instance.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := pool.compiled.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the plugins once Task is done calling them.
+func (p Plugins) Close(ctx context.Context) error {
+	var errs []error
+	for _, pool := range p {
+		if err := pool.close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Has reports whether the plugin is loaded and has the given export.
+func (p Plugins) Has(name, export string) bool {
+	pool, ok := p[name]
+	return ok && pool.exports[export]
+}
+
+// Call calls an export of a plugin. The variables are the ones task_var reads.
+func (p Plugins) Call(ctx context.Context, name, export string, vars map[string]any, input []byte) ([]byte, error) {
+	pool, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("task: Plugin %q not found", name)
+	}
+	if !pool.exports[export] {
+		return nil, fmt.Errorf("task: Plugin %q has no export %q", name, export)
+	}
+	instance, err := pool.get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("task: Plugin %q failed to start: %w", name, err)
+	}
+	instance.stdout.w, instance.stderr.w = pluginOutput(ctx)
+	instance.memory.Reset()
+	rc, out, err := instance.CallWithContext(withPluginVars(ctx, vars), export, input)
+	exceeded := instance.memory.Exceeded()
+	// An instance which trapped may be left in a broken state
+	if err != nil {
+		_ = instance.Close(context.Background())
+	} else {
+		pool.put(instance)
+	}
+	// Timeouts and memory exhaustion are imposed by Task, so they aren't
+	// blamed on the plugin even though extism reports them with an exit code
+	var exitErr *sys.ExitError
+	switch {
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == sys.ExitCodeDeadlineExceeded && ctx.Err() == nil:
+		return nil, fmt.Errorf("task: Plugin %q exceeded its timeout of %s calling %q", name, pool.plugin.Timeout, export)
+	case err != nil && exitErr == nil && exceeded:
+		return nil, fmt.Errorf("task: Plugin %q failed to call %q, it exceeded its max_memory of %s: %w", name, export, humanize.IBytes(pool.plugin.MaxMemory), err)
+	case rc != 0:
+		message := strings.TrimSpace(string(out))
+		if err != nil {
+			message = err.Error()
+		}
+		return nil, &errors.TaskPluginExitError{Plugin: name, Export: export, ExitCode: int(rc), Message: message}
+	case err != nil:
+		return nil, fmt.Errorf("task: Plugin %q failed to call %q: %w", name, export, err)
+	default:
+		return out, nil
+	}
+}
+
+// pluginFuncInput encodes the arguments of a plugin template function. A
+// single string is passed as is, a single value of another type as JSON, and
+// several arguments as a JSON array.
+func pluginFuncInput(args []any) ([]byte, error) {
+	switch len(args) {
+	case 0:
+		return nil, nil
+	case 1:
+		if s, ok := args[0].(string); ok {
+			return []byte(s), nil
+		}
+		return json.Marshal(args[0])
+	default:
+		return json.Marshal(args)
+	}
+}
+
+// pluginFuncOutput decodes the output of a plugin template function, which
+// is a JSON value or else a string. Integral numbers are decoded as ints, so
+// that they aren't rendered in exponent notation.
+func pluginFuncOutput(out []byte) any {
+	if !json.Valid(out) {
+		return string(out)
+	}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return string(out)
+	}
+	return decodeNumbers(value)
+}
+
+// decodeNumbers replaces the numbers of a value decoded with UseNumber by
+// ints, or float64s when they aren't integral or don't fit an int.
+func decodeNumbers(value any) any {
+	switch value := value.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(value.String(), 10, strconv.IntSize); err == nil {
+			return int(i)
+		}
+		f, _ := value.Float64()
+		return f
+	case []any:
+		for i, v := range value {
+			value[i] = decodeNumbers(v)
+		}
+	case map[string]any:
+		for k, v := range value {
+			value[k] = decodeNumbers(v)
+		}
+	}
+	return value
+}
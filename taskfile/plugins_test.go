@@ -0,0 +1,153 @@
+package taskfile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	extism "github.com/extism/go-sdk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tetratelabs/wazero"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/go-task/task/v3/errors"
+	"github.com/go-task/task/v3/taskfile/ast"
+)
+
+func TestPluginFuncInput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		args     []any
+		expected string
+	}{
+		{name: "none", args: nil, expected: ""},
+		{name: "string", args: []any{"foo"}, expected: "foo"},
+		{name: "number", args: []any{42}, expected: "42"},
+		{name: "map", args: []any{map[string]any{"foo": "bar"}}, expected: `{"foo":"bar"}`},
+		{name: "several", args: []any{"foo", 42, []any{true}}, expected: `["foo",42,[true]]`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			input, err := pluginFuncInput(test.args)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(input))
+		})
+	}
+}
+
+func TestPluginFuncOutput(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		out      string
+		expected any
+	}{
+		{name: "empty", out: "", expected: ""},
+		{name: "text", out: "Hello world", expected: "Hello world"},
+		{name: "string", out: `"foo"`, expected: "foo"},
+		{name: "number", out: "42", expected: 42},
+		{name: "timestamp", out: "1700000000", expected: 1700000000},
+		{name: "float", out: "4.2", expected: 4.2},
+		{name: "huge", out: "1e300", expected: 1e300},
+		{name: "list", out: `["foo",true,1]`, expected: []any{"foo", true, 1}},
+		{name: "map", out: `{"foo":"bar","n":{"m":2}}`, expected: map[string]any{"foo": "bar", "n": map[string]any{"m": 2}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, pluginFuncOutput([]byte(test.out)))
+		})
+	}
+}
+
+// noopWasm is a module exporting noop, which returns 0.
+var noopWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // header
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // type: () -> i32
+	0x03, 0x02, 0x01, 0x00, // function
+	0x07, 0x08, 0x01, 0x04, 'n', 'o', 'o', 'p', 0x00, 0x00, // export
+	0x0a, 0x06, 0x01, 0x04, 0x00, 0x41, 0x00, 0x0b, // code: i32.const 0
+}
+
+// trapWasm is a module exporting trap, which traps.
+var trapWasm = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, // header
+	0x01, 0x05, 0x01, 0x60, 0x00, 0x01, 0x7f, // type: () -> i32
+	0x03, 0x02, 0x01, 0x00, // function
+	0x07, 0x08, 0x01, 0x04, 't', 'r', 'a', 'p', 0x00, 0x00, // export
+	0x0a, 0x05, 0x01, 0x03, 0x00, 0x00, 0x0b, // code: unreachable
+}
+
+func TestPluginTrap(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	compiled, err := extism.NewCompiledPlugin(ctx,
+		extism.Manifest{Wasm: []extism.Wasm{extism.WasmData{Data: trapWasm}}},
+		extism.PluginConfig{},
+		nil,
+	)
+	require.NoError(t, err)
+	pool, err := newPluginPool(ctx, compiled, extism.PluginInstanceConfig{}, &ast.Plugin{MaxMemory: 1 << 20})
+	require.NoError(t, err)
+	plugins := Plugins{"trap": pool}
+	t.Cleanup(func() { _ = plugins.Close(context.Background()) })
+
+	// The trap isn't blamed on max_memory when the memory didn't exceed it
+	_, err = plugins.Call(ctx, "trap", "trap", nil, nil)
+	require.ErrorContains(t, err, `task: Plugin "trap" failed to call "trap": `)
+	require.NotContains(t, err.Error(), "max_memory")
+	var exitErr *errors.TaskPluginExitError
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode)
+	assert.Contains(t, exitErr.Message, "unreachable")
+}
+
+func TestPluginPool(t *testing.T) {
+	t.Parallel()
+
+	ctx := t.Context()
+	r := NewReader(WithTempDir(t.TempDir()))
+	compiled, err := extism.NewCompiledPlugin(ctx,
+		extism.Manifest{Wasm: []extism.Wasm{extism.WasmData{Data: noopWasm}}},
+		extism.PluginConfig{RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(r.pluginCompilationCache())},
+		nil,
+	)
+	require.NoError(t, err)
+	pool, err := newPluginPool(ctx, compiled, extism.PluginInstanceConfig{}, &ast.Plugin{})
+	require.NoError(t, err)
+	plugins := Plugins{"noop": pool}
+
+	assert.True(t, plugins.Has("noop", "noop"))
+	assert.False(t, plugins.Has("noop", "missing"))
+	assert.False(t, plugins.Has("missing", "noop"))
+
+	var g errgroup.Group
+	for range 16 {
+		g.Go(func() error {
+			_, err := plugins.Call(ctx, "noop", "noop", nil, nil)
+			return err
+		})
+	}
+	require.NoError(t, g.Wait())
+	assert.NotEmpty(t, pool.idle)
+	assert.LessOrEqual(t, len(pool.idle), runtime.GOMAXPROCS(0))
+
+	cached, err := os.ReadDir(filepath.Join(r.tempDir, pluginCacheDir))
+	require.NoError(t, err)
+	assert.NotEmpty(t, cached)
+
+	require.NoError(t, plugins.Close(ctx))
+	assert.Empty(t, pool.idle)
+	_, err = plugins.Call(ctx, "noop", "noop", nil, nil)
+	require.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +24,11 @@ import (
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
+// pluginTasksExport is the export of the plugins contributing tasks. It is
+// called without input when the Taskfile is read, and returns the tasks in the
+// format of the tasks section of a Taskfile, YAML or JSON.
+const pluginTasksExport = "tasks"
+
 const (
 	taskfileUntrustedPrompt = `The task you are attempting to run depends on the remote Taskfile at %q.
 --- Make sure you trust the source of this Taskfile before continuing ---
@@ -53,6 +59,7 @@ type (
 		debugFunc           DebugFunc
 		promptFunc          PromptFunc
 		promptMutex         sync.Mutex
+		pluginHost          PluginHost
 	}
 )
 
@@ -69,6 +76,7 @@ func NewReader(opts ...ReaderOption) *Reader {
 		debugFunc:           nil,
 		promptFunc:          nil,
 		promptMutex:         sync.Mutex{},
+		pluginHost:          nil,
 	}
 	r.Options(opts...)
 	return r
@@ -187,6 +195,21 @@ func (o *promptFuncOption) ApplyToReader(r *Reader) {
 	r.promptFunc = o.promptFunc
 }
 
+// WithPluginHost sets the [PluginHost] plugins reach through their host
+// functions. By default, plugins can't log nor run tasks, and only see the
+// environment of the process.
+func WithPluginHost(host PluginHost) ReaderOption {
+	return &pluginHostOption{host: host}
+}
+
+type pluginHostOption struct {
+	host PluginHost
+}
+
+func (o *pluginHostOption) ApplyToReader(r *Reader) {
+	r.pluginHost = o.host
+}
+
 // Read will read the Taskfile defined by the [Reader]'s [Node] and recurse
 // through any [ast.Includes] it finds, reading each included Taskfile and
 // building an [ast.TaskfileGraph] as it goes. If any errors occur, they will be
@@ -198,19 +221,21 @@ func (r *Reader) Read(ctx context.Context, node Node) (*ast.TaskfileGraph, error
 	return r.graph, nil
 }
 
-func (r *Reader) LoadPlugin(ctx context.Context, node Node) error {
-	var tf *ast.Taskfile
-	var err error
-	tf, err = r.readNode(ctx, node)
-	if err != nil {
-		return err
-	}
-
-	plugins := map[string]*extism.Plugin{}
+// LoadPlugin instantiates the plugins of the merged Taskfile, exposes their
+// exports as template functions and merges the tasks they contribute into the
+// Taskfile. The plugins are returned for Task to call them.
+func (r *Reader) LoadPlugin(ctx context.Context, tf *ast.Taskfile) (Plugins, error) {
+	plugins := Plugins{}
+	cache := r.pluginCompilationCache()
 	for name, value := range tf.Plugins.All() {
-
+		b, err := r.readPlugin(ctx, value)
+		if err != nil {
+			return nil, err
+		}
 		mft := extism.Manifest{
-			Wasm: []extism.Wasm{extism.WasmFile{Path: filepath.Join(node.Dir(), value.File), Name: name}},
+			Wasm:         []extism.Wasm{extism.WasmData{Data: b, Name: name}},
+			AllowedHosts: value.AllowedHosts,
+			Timeout:      uint64(value.Timeout.Milliseconds()),
 		}
 
 		moduleConfig := wazero.NewModuleConfig()
@@ -233,40 +258,133 @@ func (r *Reader) LoadPlugin(ctx context.Context, node Node) error {
 		if value.Rand {
 			moduleConfig = moduleConfig.WithRandSource(rand.Reader)
 		}
-		if value.Stderr {
-			moduleConfig = moduleConfig.WithStderr(os.Stderr)
-		}
-		if value.Stdout {
-			moduleConfig = moduleConfig.WithStderr(os.Stdout)
-		}
 		config := extism.PluginConfig{
-			EnableWasi:   true,
-			ModuleConfig: moduleConfig,
+			EnableWasi:    true,
+			RuntimeConfig: wazero.NewRuntimeConfig().WithCompilationCache(cache),
 		}
 
-		plugin, err := extism.NewPlugin(ctx, mft, config, []extism.HostFunction{})
+		compiled, err := extism.NewCompiledPlugin(ctx, mft, config, pluginHostFunctions(name, value, r.pluginHost))
 		if err != nil {
-			return err
+			return nil, err
 		}
-		plugins[name] = plugin
+		if plugins[name], err = newPluginPool(ctx, compiled, extism.PluginInstanceConfig{ModuleConfig: moduleConfig}, value); err != nil {
+			return nil, fmt.Errorf("task: Plugin %q failed to start: %w", name, err)
+		}
+	}
+
+	// Plugins of included Taskfiles are exposed under their namespaced name,
+	// lib:fmt becoming lib_fmt, and under their own name too unless another
+	// plugin has it already.
+	exposed := map[string]bool{}
+	for name := range tf.Plugins.All() {
+		exposed[name] = true
 	}
-	for pluginName, plugin := range plugins {
-		for pluginFuncName := range plugin.Module().ExportedFunctions() {
-			if slices.Contains([]string{"_initialize", "calloc", "free", "malloc", "realloc"}, pluginFuncName) {
+	for pluginName := range tf.Plugins.Keys() {
+		prefixes := []string{strings.ReplaceAll(pluginName, ast.NamespaceSeparator, "_")}
+		if i := strings.LastIndex(pluginName, ast.NamespaceSeparator); i != -1 && !exposed[pluginName[i+1:]] {
+			exposed[pluginName[i+1:]] = true
+			prefixes = append(prefixes, pluginName[i+1:])
+		}
+
+		for pluginFuncName := range plugins[pluginName].exports {
+			if slices.Contains([]string{"_initialize", "calloc", "free", "malloc", "realloc", pluginTasksExport}, pluginFuncName) {
 				continue
 			}
 
-			name := fmt.Sprintf("%s_%s", pluginName, pluginFuncName)
-			templater.ExposePluginFunc(name, func(input string) any {
-				if _, out, err := plugin.Call(pluginFuncName, []byte(input)); err != nil {
-					return ""
-				} else {
-					return string(out)
+			fn := func(ctx context.Context, vars map[string]any, args ...any) (any, error) {
+				input, err := pluginFuncInput(args)
+				if err != nil {
+					return nil, fmt.Errorf("task: Plugin %q: invalid arguments for %q: %w", pluginName, pluginFuncName, err)
 				}
-			})
+				out, err := plugins.Call(ctx, pluginName, pluginFuncName, vars, input)
+				if err != nil {
+					return nil, err
+				}
+				return pluginFuncOutput(out), nil
+			}
+			for _, prefix := range prefixes {
+				templater.ExposePluginFunc(fmt.Sprintf("%s_%s", prefix, pluginFuncName), fn)
+			}
+		}
+
+		if plugins.Has(pluginName, pluginTasksExport) {
+			out, err := plugins.Call(ctx, pluginName, pluginTasksExport, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			value, _ := tf.Plugins.Get(pluginName)
+			if err := mergePluginTasks(tf, pluginName, value, out); err != nil {
+				return nil, err
+			}
 		}
 	}
-	return nil
+	return plugins, nil
+}
+
+// pluginCompilationCache returns the cache of the compiled plugins. It is kept
+// in the temp dir, so that plugins are only compiled again once they changed.
+func (r *Reader) pluginCompilationCache() wazero.CompilationCache {
+	cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(r.tempDir, pluginCacheDir))
+	if err != nil {
+		r.debugf("task: Compiled plugins are not cached: %v\n", err)
+		return wazero.NewCompilationCache()
+	}
+	return cache
+}
+
+// readPlugin reads the file of a plugin. Remote plugins are read through the
+// cache of remote Taskfiles, and all of them are checked against the checksum
+// they are pinned to.
+func (r *Reader) readPlugin(ctx context.Context, plugin *ast.Plugin) ([]byte, error) {
+	node, err := NewNode(plugin.Location, plugin.Dir, r.insecure,
+		WithChecksum(plugin.Checksum),
+		WithContentTypes(pluginContentTypes...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.readNodeContent(ctx, node)
+	if err != nil {
+		return nil, err
+	}
+	// Cached copies of remote files are not verified when read
+	if checksum := checksum(b); !node.Verify(checksum) {
+		return nil, &errors.TaskfileDoesNotMatchChecksum{
+			URI:              node.Location(),
+			ExpectedChecksum: node.Checksum(),
+			ActualChecksum:   checksum,
+		}
+	}
+	return b, nil
+}
+
+// mergePluginTasks merges the tasks listed by a plugin into the Taskfile, as if
+// the Taskfile declaring the plugin included a Taskfile with these tasks under
+// the namespace of the plugin.
+func mergePluginTasks(tf *ast.Taskfile, name string, plugin *ast.Plugin, b []byte) error {
+	var tasks ast.Tasks
+	if err := yaml.Unmarshal(b, &tasks); err != nil {
+		taskfileDecodeErr := &errors.TaskfileDecodeError{}
+		if errors.As(err, &taskfileDecodeErr) {
+			snippet := NewSnippet(b,
+				WithLine(taskfileDecodeErr.Line),
+				WithColumn(taskfileDecodeErr.Column),
+				WithPadding(2),
+			)
+			return taskfileDecodeErr.WithFileInfo(plugin.Location, snippet.String())
+		}
+		return &errors.TaskfileInvalidError{URI: filepathext.TryAbsToRel(plugin.Location), Err: err}
+	}
+	for task := range tasks.Values(nil) {
+		task.Location.Taskfile = plugin.Location
+	}
+
+	include := &ast.Include{
+		Namespace:      name,
+		Dir:            plugin.Dir,
+		AdvancedImport: true,
+	}
+	return tf.Tasks.Merge(&tasks, include, tf.Vars)
 }
 
 func (r *Reader) debugf(format string, a ...any) {
@@ -420,6 +538,14 @@ func (r *Reader) readNode(ctx context.Context, node Node) (*ast.Taskfile, error)
 		return nil, &errors.TaskfileVersionCheckError{URI: node.Location()}
 	}
 
+	// Resolve the location of the plugins relative to the Taskfile
+	for plugin := range tf.Plugins.Values() {
+		if plugin.Location, err = node.ResolveEntrypoint(plugin.File); err != nil {
+			return nil, err
+		}
+		plugin.Dir = node.Dir()
+	}
+
 	// Set the taskfile/task's locations
 	tf.Location = node.Location()
 	for task := range tf.Tasks.Values(nil) {
@@ -29,14 +29,24 @@ var (
 		"application/yaml",
 		"application/x-yaml",
 	}
+	// pluginContentTypes are the content types allowed for remote plugins,
+	// instead of the ones of Taskfiles.
+	pluginContentTypes = []string{
+		"application/wasm",
+	}
 )
 
 // RemoteExists will check if a file at the given URL Exists. If it does, it
 // will return its URL. If it does not, it will search the search for any files
 // at the given URL with any of the default Taskfile files names. If any of
 // these match a file, the first matching path will be returned. If no files are
-// found, an error will be returned.
-func RemoteExists(ctx context.Context, u url.URL) (*url.URL, error) {
+// found, an error will be returned. The content types default to the ones of
+// Taskfiles.
+func RemoteExists(ctx context.Context, u url.URL, contentTypes ...string) (*url.URL, error) {
+	if len(contentTypes) == 0 {
+		contentTypes = allowedContentTypes
+	}
+
 	// Create a new HEAD request for the given URL to check if the resource exists
 	req, err := http.NewRequestWithContext(ctx, "HEAD", u.String(), nil)
 	if err != nil {
@@ -58,7 +68,7 @@ func RemoteExists(ctx context.Context, u url.URL) (*url.URL, error) {
 	// Taskfiles It means we can try other files instead of downloading
 	// something that is definitely not a Taskfile
 	contentType := resp.Header.Get("Content-Type")
-	if resp.StatusCode == http.StatusOK && slices.ContainsFunc(allowedContentTypes, func(s string) bool {
+	if resp.StatusCode == http.StatusOK && slices.ContainsFunc(contentTypes, func(s string) bool {
 		return strings.Contains(contentType, s)
 	}) {
 		return &u, nil
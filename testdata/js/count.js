@@ -0,0 +1,4 @@
+import * as std from "qjs:std";
+
+const input = std.in.readAsString();
+print(input.length, input.split("\n").length - 1);
@@ -0,0 +1,176 @@
+//go:build wasip1
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//go:wasmexport greet
+func greet() int32 {
+	who, _ := read(taskVar(write(input())))
+	name, _ := read(taskVar(write("NAME")))
+	return output("Hello " + who + " from " + name)
+}
+
+//go:wasmexport env
+func env() int32 {
+	value, ok := read(taskEnv(write(input())))
+	if !ok {
+		return output("<unset>")
+	}
+	return output(value)
+}
+
+//go:wasmexport log
+func log() int32 {
+	message := input()
+	for level := range int32(4) {
+		taskLog(level, write(message))
+	}
+	return output("")
+}
+
+//go:wasmexport run
+func run() int32 {
+	if err, failed := read(taskRun(write(input()), write(`{"MSG":"from plugin"}`))); failed {
+		return output(err)
+	}
+	return output("ok")
+}
+
+//go:wasmexport args
+func args() int32 {
+	response, _ := json.Marshal(map[string]string{"input": input()})
+	return output(string(response))
+}
+
+//go:wasmexport fail
+func failing() int32 {
+	return fail(input())
+}
+
+// exit returns its input as exit code.
+//
+//go:wasmexport exit
+func exit() int32 {
+	var code int32
+	_, _ = fmt.Sscan(input(), &code)
+	output(fmt.Sprintf("exiting with %d", code))
+	return code
+}
+
+// print writes its input to stdout, stderr and the Task logger.
+//
+//go:wasmexport print
+func printing() int32 {
+	message := input()
+	fmt.Println("stdout " + message)
+	fmt.Fprintln(os.Stderr, "stderr "+message)
+	taskLog(1, write("log "+message))
+	return output("output " + message)
+}
+
+//go:wasmexport tasks
+func tasks() int32 {
+	return output(`
+default:
+  cmd: echo "default from plugin"
+
+hello:
+  vars:
+    WHO: plugin
+  cmd: echo '{{host_greet "WHO"}}'
+`)
+}
+
+// fingerprint considers sources named *.up-to-date as up to date.
+//
+//go:wasmexport fingerprint
+func fingerprint() int32 {
+	var request struct {
+		Check   string   `json:"check"`
+		Sources []string `json:"sources"`
+	}
+	if err := json.Unmarshal([]byte(input()), &request); err != nil {
+		return output("")
+	}
+	upToDate := len(request.Sources) > 0
+	for _, source := range request.Sources {
+		if !strings.HasSuffix(source, ".up-to-date") {
+			upToDate = false
+		}
+	}
+	response, _ := json.Marshal(map[string]any{
+		"up_to_date": upToDate,
+		"value":      fmt.Sprintf("%d sources", len(request.Sources)),
+	})
+	return output(string(response))
+}
+
+//go:wasmexport before_task
+func beforeTask() int32 {
+	return hook()
+}
+
+//go:wasmexport after_task
+func afterTask() int32 {
+	return hook()
+}
+
+//go:wasmexport on_error
+func onError() int32 {
+	return hook()
+}
+
+// hook forbids the tasks named forbidden, fails the on_error hook of the
+// tasks named fail-hook, and logs the events of the tasks setting HOOKS.
+func hook() int32 {
+	var event struct {
+		Hook     string `json:"hook"`
+		Task     string `json:"task"`
+		ExitCode int    `json:"exit_code"`
+	}
+	if err := json.Unmarshal([]byte(input()), &event); err != nil {
+		return fail(err.Error())
+	}
+	if event.Hook == "before_task" && event.Task == "forbidden" {
+		return fail("task names must not be forbidden")
+	}
+	if event.Hook == "on_error" && event.Task == "fail-hook" {
+		return fail("on_error hook failed")
+	}
+	if _, ok := read(taskVar(write("HOOKS"))); ok {
+		taskLog(1, write(fmt.Sprintf("%s %s %d", event.Hook, event.Task, event.ExitCode)))
+	}
+	return output("")
+}
+
+func main() {}
+
+// allocate allocates as many MiB as its input.
+//
+//go:wasmexport allocate
+func allocate() int32 {
+	var mib int
+	_, _ = fmt.Sscan(input(), &mib)
+	var allocated [][]byte
+	for range mib {
+		allocated = append(allocated, make([]byte, 1<<20))
+	}
+	return output(fmt.Sprintf("%d MiB", len(allocated)))
+}
+
+// spin never returns.
+//
+//go:wasmexport spin
+func spin() int32 {
+	for n := 0; ; n++ {
+		if n < 0 {
+			return output("")
+		}
+	}
+}
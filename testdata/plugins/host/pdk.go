@@ -0,0 +1,81 @@
+//go:build wasip1
+
+package main
+
+// Minimal bindings of the Extism kernel and of the Task host functions, so
+// that the plugin builds with the standard Go toolchain alone:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o host.wasm .
+
+//go:wasmimport extism:host/env input_length
+func inputLength() uint64
+
+//go:wasmimport extism:host/env input_load_u8
+func inputLoadU8(offset uint64) uint32
+
+//go:wasmimport extism:host/env alloc
+func alloc(n uint64) uint64
+
+//go:wasmimport extism:host/env length
+func length(offset uint64) uint64
+
+//go:wasmimport extism:host/env load_u8
+func loadU8(offset uint64) uint32
+
+//go:wasmimport extism:host/env store_u8
+func storeU8(offset uint64, v uint32)
+
+//go:wasmimport extism:host/env output_set
+func outputSet(offset, n uint64)
+
+//go:wasmimport extism:host/env error_set
+func errorSet(offset uint64)
+
+//go:wasmimport extism:host/user task_var
+func taskVar(name uint64) uint64
+
+//go:wasmimport extism:host/user task_env
+func taskEnv(name uint64) uint64
+
+//go:wasmimport extism:host/user task_log
+func taskLog(level int32, message uint64)
+
+//go:wasmimport extism:host/user task_run
+func taskRun(name, vars uint64) uint64
+
+func input() string {
+	b := make([]byte, inputLength())
+	for i := range b {
+		b[i] = byte(inputLoadU8(uint64(i)))
+	}
+	return string(b)
+}
+
+func output(s string) int32 {
+	outputSet(write(s), uint64(len(s)))
+	return 0
+}
+
+func fail(message string) int32 {
+	errorSet(write(message))
+	return 1
+}
+
+func write(s string) uint64 {
+	offset := alloc(uint64(len(s)))
+	for i := range len(s) {
+		storeU8(offset+uint64(i), uint32(s[i]))
+	}
+	return offset
+}
+
+func read(offset uint64) (string, bool) {
+	if offset == 0 {
+		return "", false
+	}
+	b := make([]byte, length(offset))
+	for i := range b {
+		b[i] = byte(loadU8(offset + uint64(i)))
+	}
+	return string(b), true
+}
@@ -1,6 +1,7 @@
 package task
 
 import (
+	"context"
 	"fmt"
 	"maps"
 	"net/url"
@@ -19,21 +20,25 @@ import (
 	"github.com/go-task/task/v3/internal/filepathext"
 	"github.com/go-task/task/v3/internal/fingerprint"
 	"github.com/go-task/task/v3/internal/templater"
+	"github.com/go-task/task/v3/taskfile"
 	"github.com/go-task/task/v3/taskfile/ast"
 )
 
 // CompiledTask returns a copy of a task, but replacing variables in almost all
 // properties using the Go template package.
 func (e *Executor) CompiledTask(call *Call) (*ast.Task, error) {
-	return e.compiledTask(call, true)
+	return e.compiledTask(context.Background(), call, true)
 }
 
 // FastCompiledTask is like CompiledTask, but it skippes dynamic variables.
 func (e *Executor) FastCompiledTask(call *Call) (*ast.Task, error) {
-	return e.compiledTask(call, false)
+	return e.compiledTask(context.Background(), call, false)
 }
 
-func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, error) {
+// compiledTask compiles the task for a run with the given context, which the
+// plugin functions of its templates are called with. They write to the output
+// of the task, as its commands do.
+func (e *Executor) compiledTask(ctx context.Context, call *Call, evaluateShVars bool) (_ *ast.Task, err error) {
 	origTask, err := e.GetTask(call)
 	if err != nil {
 		return nil, err
@@ -55,7 +60,20 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 		}
 	}
 
-	cache := &templater.Cache{Vars: vars}
+	cache := &templater.Cache{Vars: vars, Ctx: taskfile.WithPluginOutput(ctx, e.Stdout, e.Stderr)}
+	prefix := templater.Replace(origTask.Prefix, cache)
+	if prefix == "" {
+		prefix = origTask.Task
+	}
+	if e.Output != nil {
+		stdOut, stdErr, closer := e.taskOutput(origTask, call).WrapWriter(e.Stdout, e.Stderr, prefix, cache)
+		defer func() {
+			if closeErr := closer(err); err == nil {
+				err = closeErr
+			}
+		}()
+		cache.Ctx = taskfile.WithPluginOutput(ctx, stdOut, stdErr)
+	}
 	new := ast.Task{
 		Task:                 origTask.Task,
 		Label:                templater.Replace(origTask.Label, cache),
@@ -75,13 +93,14 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 		Interactive:          origTask.Interactive,
 		Internal:             origTask.Internal,
 		Method:               templater.Replace(origTask.Method, cache),
-		Prefix:               templater.Replace(origTask.Prefix, cache),
+		Prefix:               prefix,
 		IgnoreError:          origTask.IgnoreError,
 		Run:                  templater.Replace(origTask.Run, cache),
 		IncludeVars:          origTask.IncludeVars,
 		IncludedTaskfileVars: origTask.IncludedTaskfileVars,
 		Platforms:            origTask.Platforms,
-		Ssh:                  origTask.Ssh,
+		Ssh:                  origTask.Ssh.DeepCopy(),
+		InterpOptions:        origTask.InterpOptions,
 		Location:             origTask.Location,
 		Requires:             origTask.Requires,
 		Watch:                origTask.Watch,
@@ -95,9 +114,6 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 	if e.Dir != "" {
 		new.Dir = filepathext.SmartJoin(e.Dir, new.Dir)
 	}
-	if new.Prefix == "" {
-		new.Prefix = new.Task
-	}
 
 	dotenvEnvs := ast.NewVars()
 	if len(new.Dotenv) > 0 {
@@ -125,22 +141,28 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 	if evaluateShVars {
 		for k, v := range new.Env.All() {
 			// If the variable is not dynamic, we can set it and return
-			if v.Value != nil || v.Sh == nil {
+			if v.Value != nil || v.Sh == nil && v.Plugin == "" {
 				new.Env.Set(k, ast.Var{Value: v.Value})
 				continue
 			}
-			static, err := e.Compiler.HandleDynamicVar(v, new.Dir, env.GetFromVars(new.Env))
+			static, err := e.Compiler.HandleDynamicVar(v, origTask, new.Dir, env.GetFromVars(new.Env), new.Vars)
 			if err != nil {
 				return nil, err
 			}
 			new.Env.Set(k, ast.Var{Value: static})
 		}
 	}
+	// Templates from now on call plugins with the env of the task
+	cache.Ctx = withPluginEnv(cache.Ctx, new.Env)
 
 	if len(origTask.Sources) > 0 && origTask.Method != "none" {
 		var checker fingerprint.SourcesCheckable
 
-		if origTask.Method == "timestamp" {
+		if _, _, isPlugin := strings.Cut(origTask.Method, "."); isPlugin {
+			if checker, err = e.sourcesChecker(&new, origTask.Method); err != nil {
+				return nil, err
+			}
+		} else if origTask.Method == "timestamp" {
 			checker = fingerprint.NewTimestampChecker(e.TempDir.Fingerprint, e.Dry)
 		} else {
 			checker = fingerprint.NewChecksumChecker(e.TempDir.Fingerprint, e.Dry)
@@ -167,62 +189,8 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 		}
 	}
 
-	if len(origTask.Cmds) > 0 {
-		new.Cmds = make([]*ast.Cmd, 0, len(origTask.Cmds))
-		for _, cmd := range origTask.Cmds {
-			if cmd == nil {
-				continue
-			}
-
-			if cmd.If != nil {
-				if len(cmd.If.Value) > 0 {
-					cmd.If = templater.Replace(cmd.If, cache)
-				}
-				if cmd.If.Sh != nil {
-					cmd.If.Sh = *templater.Replace(&cmd.If.Sh, cache)
-				}
-			}
-
-			if cmd.For != nil {
-				list, keys, err := itemsFromFor(cmd.For, new.Dir, new.Sources, new.Generates, vars, origTask.Location, cache)
-				if err != nil {
-					return nil, err
-				}
-				// Name the iterator variable
-				var as string
-				if cmd.For.As != "" {
-					as = cmd.For.As
-				} else {
-					as = "ITEM"
-				}
-				// Create a new command for each item in the list
-				for i, loopValue := range list {
-					extra := map[string]any{
-						as: loopValue,
-					}
-					if len(keys) > 0 {
-						extra["KEY"] = keys[i]
-					}
-					newCmd := cmd.DeepCopy()
-					newCmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
-					newCmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, extra)
-					newCmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, extra)
-					new.Cmds = append(new.Cmds, newCmd)
-				}
-				continue
-			}
-			// Defer commands are replaced in a lazy manner because
-			// we need to include EXIT_CODE.
-			if cmd.Defer {
-				new.Cmds = append(new.Cmds, cmd.DeepCopy())
-				continue
-			}
-			newCmd := cmd.DeepCopy()
-			newCmd.Cmd = templater.Replace(cmd.Cmd, cache)
-			newCmd.Task = templater.Replace(cmd.Task, cache)
-			newCmd.Vars = templater.ReplaceVars(cmd.Vars, cache)
-			new.Cmds = append(new.Cmds, newCmd)
-		}
+	if new.Cmds, err = compileCmds(origTask, &new, vars, cache); err != nil {
+		return nil, err
 	}
 	if len(origTask.Deps) > 0 {
 		new.Deps = make([]*ast.Dep, 0, len(origTask.Deps))
@@ -277,7 +245,48 @@ func (e *Executor) compiledTask(call *Call, evaluateShVars bool) (*ast.Task, err
 		}
 	}
 
-	if err := compileSsh(origTask.Ssh, cache, nil); err != nil {
+	if err := compileSsh(new.Ssh, cache, nil); err != nil {
+		return nil, errors.TaskfileInvalidError{
+			URI: origTask.Location.Taskfile,
+			Err: err,
+		}
+	}
+
+	if new.Ssh != nil && new.Ssh.For != nil {
+		list, keys, err := itemsFromFor(new.Ssh.For, new.Dir, new.Sources, new.Generates, vars, origTask.Location, cache)
+		if err != nil {
+			return nil, err
+		}
+		// Name the iterator variable
+		var as string
+		if new.Ssh.For.As != "" {
+			as = new.Ssh.For.As
+		} else {
+			as = "ITEM"
+		}
+		// Create a new host for each item in the list
+		new.Ssh.Hosts = make([]*ast.Ssh, 0, len(list))
+		for i, loopValue := range list {
+			extra := map[string]any{
+				as: loopValue,
+			}
+			if len(keys) > 0 {
+				extra["KEY"] = keys[i]
+			}
+			host := origTask.Ssh.DeepCopy()
+			host.For = nil
+			host.FailFast = false
+			if err := compileSsh(host, cache, extra); err != nil {
+				return nil, errors.TaskfileInvalidError{
+					URI: origTask.Location.Taskfile,
+					Err: err,
+				}
+			}
+			new.Ssh.Hosts = append(new.Ssh.Hosts, host)
+		}
+		new.Ssh.For = nil
+	}
+	if err := checkSshDownloads(new.Ssh, new.Dir); err != nil {
 		return nil, errors.TaskfileInvalidError{
 			URI: origTask.Location.Taskfile,
 			Err: err,
@@ -300,6 +309,10 @@ func compileSsh(ssh *ast.Ssh, cache *templater.Cache, extra map[string]any) erro
 	if ssh == nil {
 		return nil
 	}
+	// Hosts generated by for are compiled once per item
+	if ssh.For != nil {
+		return nil
+	}
 	if len(ssh.Url) > 0 && len(ssh.Addr) <= 0 {
 		var compiled string
 		if extra == nil {
@@ -311,6 +324,7 @@ func compileSsh(ssh *ast.Ssh, cache *templater.Cache, extra map[string]any) erro
 		if err != nil {
 			return err
 		}
+		ssh.Name = templater.ReplaceWithExtra(ssh.Name, cache, extra)
 		ssh.Addr = parsed.Host
 		ssh.User = parsed.User.Username()
 		ssh.Password, _ = parsed.User.Password()
@@ -323,13 +337,26 @@ func compileSsh(ssh *ast.Ssh, cache *templater.Cache, extra map[string]any) erro
 			}
 		}
 		ssh.Insecure = parsed.Query().Has("insecure")
+		ssh.Tofu = parsed.Query().Has("tofu")
+		for _, jump := range parsed.Query()["jump"] {
+			hop, err := url.Parse("//" + jump)
+			if err != nil {
+				return err
+			}
+			password, _ := hop.User.Password()
+			ssh.Jump = append(ssh.Jump, &ast.Ssh{
+				Addr:     hop.Host,
+				User:     hop.User.Username(),
+				Password: password,
+			})
+		}
 	} else {
 		valueOf := reflect.ValueOf(ssh)
 		for i := 0; i < valueOf.Elem().NumField(); i++ {
 			field := valueOf.Elem().Type().Field(i)
 			value := valueOf.Elem().Field(i)
 			if value.CanSet() &&
-				!slices.Contains([]string{"Url", "Uploads"}, field.Name) &&
+				!slices.Contains([]string{"Url", "Jump", "Uploads", "Downloads", "Forwards"}, field.Name) &&
 				field.Type.Name() == "string" {
 				if extra == nil {
 					value.SetString(templater.Replace(value.String(), cache))
@@ -338,10 +365,102 @@ func compileSsh(ssh *ast.Ssh, cache *templater.Cache, extra map[string]any) erro
 				}
 			}
 		}
+		for _, jump := range ssh.Jump {
+			if err := compileSsh(jump, cache, extra); err != nil {
+				return err
+			}
+		}
+	}
+	ssh.InheritJumps()
+	for _, transfers := range [][]ast.SshUpload{ssh.Uploads, ssh.Downloads} {
+		for i := range transfers {
+			transfers[i].Source = templater.ReplaceWithExtra(transfers[i].Source, cache, extra)
+			transfers[i].Target = templater.ReplaceWithExtra(transfers[i].Target, cache, extra)
+		}
+	}
+	for i := range ssh.Forwards {
+		ssh.Forwards[i].Local = templater.ReplaceWithExtra(ssh.Forwards[i].Local, cache, extra)
+		ssh.Forwards[i].Remote = templater.ReplaceWithExtra(ssh.Forwards[i].Remote, cache, extra)
+	}
+	// Hosts inherit the settings of the mapping they are listed in
+	for _, host := range ssh.Hosts {
+		if err := compileSsh(host, cache, extra); err != nil {
+			return err
+		}
+		host.Inherit(ssh)
+		host.InheritJumps()
 	}
 	return nil
 }
 
+// compileCmds templates the commands of the compiled task t, with the for
+// loops expanded. Deferred commands are templated once they run.
+func compileCmds(origTask, t *ast.Task, vars *ast.Vars, cache *templater.Cache) ([]*ast.Cmd, error) {
+	if len(origTask.Cmds) == 0 {
+		return nil, nil
+	}
+	cmds := make([]*ast.Cmd, 0, len(origTask.Cmds))
+	for _, cmd := range origTask.Cmds {
+		if cmd == nil {
+			continue
+		}
+
+		if cmd.If != nil {
+			if len(cmd.If.Value) > 0 {
+				cmd.If = templater.Replace(cmd.If, cache)
+			}
+			if cmd.If.Sh != nil {
+				cmd.If.Sh = *templater.Replace(&cmd.If.Sh, cache)
+			}
+		}
+
+		if cmd.For != nil {
+			list, keys, err := itemsFromFor(cmd.For, t.Dir, t.Sources, t.Generates, vars, origTask.Location, cache)
+			if err != nil {
+				return nil, err
+			}
+			// Name the iterator variable
+			var as string
+			if cmd.For.As != "" {
+				as = cmd.For.As
+			} else {
+				as = "ITEM"
+			}
+			// Create a new command for each item in the list
+			for i, loopValue := range list {
+				extra := map[string]any{
+					as: loopValue,
+				}
+				if len(keys) > 0 {
+					extra["KEY"] = keys[i]
+				}
+				newCmd := cmd.DeepCopy()
+				newCmd.Cmd = templater.ReplaceWithExtra(cmd.Cmd, cache, extra)
+				newCmd.Task = templater.ReplaceWithExtra(cmd.Task, cache, extra)
+				newCmd.Vars = templater.ReplaceVarsWithExtra(cmd.Vars, cache, extra)
+				newCmd.Plugin = templater.ReplaceWithExtra(cmd.Plugin, cache, extra)
+				newCmd.Input = templater.ReplaceWithExtra(cmd.Input, cache, extra)
+				cmds = append(cmds, newCmd)
+			}
+			continue
+		}
+		// Defer commands are replaced in a lazy manner because
+		// we need to include EXIT_CODE.
+		if cmd.Defer {
+			cmds = append(cmds, cmd.DeepCopy())
+			continue
+		}
+		newCmd := cmd.DeepCopy()
+		newCmd.Cmd = templater.Replace(cmd.Cmd, cache)
+		newCmd.Task = templater.Replace(cmd.Task, cache)
+		newCmd.Vars = templater.ReplaceVars(cmd.Vars, cache)
+		newCmd.Plugin = templater.Replace(cmd.Plugin, cache)
+		newCmd.Input = templater.Replace(cmd.Input, cache)
+		cmds = append(cmds, newCmd)
+	}
+	return cmds, nil
+}
+
 func asAnySlice[T any](slice []T) []any {
 	ret := make([]any, len(slice))
 	for i, v := range slice {
@@ -410,7 +529,7 @@ func itemsFromFor(
 			// If the variable is dynamic, then it hasn't been resolved yet
 			// and we can't use it as a list. This happens when fast compiling a task
 			// for use in --list or --list-all etc.
-			if ok && v.Value != nil && v.Sh == nil {
+			if ok && v.Value != nil && v.Sh == nil && v.Plugin == "" {
 				switch value := v.Value.(type) {
 				case string:
 					if f.Split != "" {